@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hoalong/lume-fleet/fleet"
+	"github.com/spf13/cobra"
+)
+
+var addOpts struct {
+	os         string
+	cpu        int
+	memory     string
+	diskSize   string
+	sharedDirs []string
+	unattended string
+	image      string
+	vncPort    int
+	storage    string
+	tags       []string
+	labels     []string
+	autostart  bool
+	extends    []string
+}
+
+var addCmd = &cobra.Command{
+	Use:   "add <vm>",
+	Short: "Add a VM to fleet.yml",
+	Long: `Add a VM to the first config file. Only the flags given are written;
+everything else is inherited from defaults and profiles.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		doc, err := fleet.OpenDocument(cfgFiles[0])
+		if err != nil {
+			return err
+		}
+		if err := doc.AddVM(name, fleet.VMSpec{}); err != nil {
+			return err
+		}
+
+		fields, err := addFields(cmd)
+		if err != nil {
+			return err
+		}
+		for _, f := range fields {
+			if err := doc.Set("vms."+name+"."+f.key, f.value); err != nil {
+				return err
+			}
+		}
+
+		if err := saveDocument(doc); err != nil {
+			return err
+		}
+		fmt.Printf("[+] %s: added to %s\n", name, doc.Path())
+		return nil
+	},
+}
+
+type addField struct {
+	key   string
+	value any
+}
+
+// addFields returns the VM fields for the flags that were set, in the order
+// fleet.yml lists them.
+func addFields(cmd *cobra.Command) ([]addField, error) {
+	var fields []addField
+	add := func(flag, key string, value any) {
+		if cmd.Flags().Changed(flag) {
+			fields = append(fields, addField{key, value})
+		}
+	}
+
+	add("extends", "extends", addOpts.extends)
+	add("os", "os", addOpts.os)
+	add("cpu", "cpu", addOpts.cpu)
+	add("memory", "memory", addOpts.memory)
+	add("disk-size", "disk-size", addOpts.diskSize)
+	if cmd.Flags().Changed("shared-dir") {
+		var dirs []fleet.SharedDir
+		for _, d := range addOpts.sharedDirs {
+			path, ro := strings.CutSuffix(d, ":ro")
+			dirs = append(dirs, fleet.SharedDir{Path: path, ReadOnly: ro})
+		}
+		fields = append(fields, addField{"shared-dirs", dirs})
+	}
+	add("unattended", "unattended", addOpts.unattended)
+	add("image", "image", addOpts.image)
+	add("vnc-port", "vnc-port", addOpts.vncPort)
+	add("storage", "storage", addOpts.storage)
+	add("tag", "tags", addOpts.tags)
+	if cmd.Flags().Changed("label") {
+		labels := map[string]string{}
+		for _, l := range addOpts.labels {
+			k, v, ok := strings.Cut(l, "=")
+			if !ok {
+				return nil, fmt.Errorf("invalid --label %q (want key=value)", l)
+			}
+			labels[k] = v
+		}
+		fields = append(fields, addField{"labels", labels})
+	}
+	add("autostart", "autostart", addOpts.autostart)
+	return fields, nil
+}
+
+func init() {
+	f := addCmd.Flags()
+	f.StringVar(&addOpts.os, "os", "", "guest OS (macos or linux)")
+	f.IntVar(&addOpts.cpu, "cpu", 0, "CPU count")
+	f.StringVar(&addOpts.memory, "memory", "", "memory size, e.g. 8GB")
+	f.StringVar(&addOpts.diskSize, "disk-size", "", "disk size, e.g. 50GB")
+	f.StringArrayVar(&addOpts.sharedDirs, "shared-dir", nil, "host directory to share, path[:ro] (repeatable)")
+	f.StringVar(&addOpts.unattended, "unattended", "", "macOS unattended preset")
+	f.StringVar(&addOpts.image, "image", "", "macOS IPSW path/latest or Linux ISO path")
+	f.IntVar(&addOpts.vncPort, "vnc-port", 0, "VNC port (0 for auto)")
+	f.StringVar(&addOpts.storage, "storage", "", "named storage location")
+	f.StringArrayVar(&addOpts.tags, "tag", nil, "tag (repeatable)")
+	f.StringArrayVar(&addOpts.labels, "label", nil, "label key=value (repeatable)")
+	f.BoolVar(&addOpts.autostart, "autostart", true, "start the VM on up")
+	f.StringArrayVar(&addOpts.extends, "extends", nil, "profile to extend (repeatable)")
+	rootCmd.AddCommand(addCmd)
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/hoalong/lume-fleet/fleet"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Read and edit fleet.yml without breaking its formatting",
+	Long: `Read and edit values in the first config file by dotted path, such as
+vms.dev-main.cpu or defaults.tags. Edits keep comments, key order and
+anchors, and are only written if the resulting config is valid.`,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <path>",
+	Short: "Print the value at a dotted path",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		doc, err := fleet.OpenDocument(cfgFiles[0])
+		if err != nil {
+			return err
+		}
+		node, err := doc.Get(args[0])
+		if err != nil {
+			return err
+		}
+		if node.Kind == yaml.ScalarNode {
+			fmt.Println(node.Value)
+			return nil
+		}
+		out := yaml.NewEncoder(os.Stdout)
+		out.SetIndent(2)
+		if err := out.Encode(node); err != nil {
+			return err
+		}
+		return out.Close()
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <path> <value>",
+	Short: "Set the value at a dotted path",
+	Long: `Set the value at a dotted path. The value is parsed as YAML, so "8" is a
+number, "[ci, dev]" is a list and "true" is a boolean.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		doc, err := fleet.OpenDocument(cfgFiles[0])
+		if err != nil {
+			return err
+		}
+		if err := doc.SetText(args[0], args[1]); err != nil {
+			return err
+		}
+		if err := saveDocument(doc); err != nil {
+			return err
+		}
+		fmt.Printf("[+] %s = %s\n", args[0], args[1])
+		return nil
+	},
+}
+
+var configMigrateCmd = &cobra.Command{
+	Use:   "migrate [file ...]",
+	Short: "Upgrade config files to the latest schema version",
+	Long: `Rewrite config files (default: every --config file) to the latest schema
+version, keeping comments and ordering. Older files that use something a
+later version changed keep working without this, but print a deprecation
+warning every time they are loaded.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		paths := args
+		if len(paths) == 0 {
+			paths = cfgFiles
+		}
+		for _, path := range paths {
+			doc, err := fleet.OpenDocument(path)
+			if err != nil {
+				return err
+			}
+			applied, err := doc.Migrate()
+			if err != nil {
+				return err
+			}
+			if len(applied) == 0 {
+				fmt.Printf("[ ] %s: nothing to migrate\n", path)
+				continue
+			}
+			if err := doc.Save(); err != nil {
+				return err
+			}
+			for _, m := range applied {
+				fmt.Printf("[>] %s: v%d -> v%d: %s\n", path, m.From, m.From+1, m.Description)
+			}
+			fmt.Printf("[+] %s: migrated to version %d\n", path, fleet.CurrentVersion)
+		}
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configGetCmd, configSetCmd, configMigrateCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+// saveDocument checks an edited document against the full config and
+// writes it only if the result is valid.
+func saveDocument(doc *fleet.Document) error {
+	if err := doc.Check(loadOptions()); err != nil {
+		return err
+	}
+	return doc.Save()
+}
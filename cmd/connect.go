@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hoalong/lume-fleet/fleet"
+	"github.com/hoalong/lume-fleet/lume"
+	"github.com/hoalong/lume-fleet/remote"
+	"github.com/spf13/cobra"
+)
+
+// defaultStartWait is how long --start waits for a VM to get an IP address
+// and accept SSH connections when --wait is not given.
+const defaultStartWait = 3 * time.Minute
+
+// connectOptions holds the flags shared by commands that connect to VMs.
+type connectOptions struct {
+	wait  time.Duration
+	start bool
+}
+
+func (o *connectOptions) register(cmd *cobra.Command) {
+	cmd.Flags().DurationVar(&o.wait, "wait", 0, "wait up to this long for the VM to get an IP address and accept SSH connections")
+	cmd.Flags().BoolVar(&o.start, "start", false, "start the VM first if it is stopped")
+}
+
+// findVM returns the VM with the given name.
+func findVM(vms []fleet.ResolvedVM, name string) (fleet.ResolvedVM, error) {
+	for _, vm := range vms {
+		if vm.Name == name {
+			return vm, nil
+		}
+	}
+	return fleet.ResolvedVM{}, fmt.Errorf("VM %q is not in the fleet", name)
+}
+
+// sshTarget returns the SSH endpoint of vm at ip.
+func sshTarget(vm fleet.ResolvedVM, ip string) remote.Target {
+	return remote.Target{
+		Name:         vm.Name,
+		Host:         ip,
+		User:         vm.SSH.User,
+		Port:         vm.SSH.Port,
+		IdentityFile: vm.SSH.IdentityFile,
+		Options:      vm.SSH.Options,
+	}
+}
+
+// pollInterval is how often commands poll lume while waiting for a VM.
+// Tests shorten it.
+var pollInterval = 2 * time.Second
+
+// checkCanStart reports why vm cannot be started outside of up: the macOS
+// limit or the host budget, as up would plan it.
+func checkCanStart(cfg *fleet.FleetConfig, vm fleet.ResolvedVM, actual []lume.VM) error {
+	limits, _, err := cfg.UpLimits(nil, actual, false)
+	if err != nil {
+		return err
+	}
+	// Started on request, whatever autostart says.
+	vm.Autostart = true
+	for _, a := range fleet.PlanUp([]fleet.ResolvedVM{vm}, actual, limits) {
+		if a.Type == fleet.ActionSkip {
+			return errors.New(a.Reason)
+		}
+	}
+	return nil
+}
+
+// connect makes sure vm is running and returns its SSH endpoint. A stopped
+// VM is started with --start; --wait waits for its IP address and SSH port.
+// Progress goes to stderr so that stdout carries only remote output.
+func (o connectOptions) connect(cfg *fleet.FleetConfig, vm fleet.ResolvedVM) (remote.Target, error) {
+	actual, err := listVMsViaCLI()
+	if err != nil {
+		return remote.Target{}, fmt.Errorf("cannot list VMs via lume CLI: %w", err)
+	}
+	current, ok := lumeVM(actual, vm.Name)
+	if !ok {
+		return remote.Target{}, fmt.Errorf("%s is not created; run `lume-fleet up %s`", vm.Name, vm.Name)
+	}
+
+	wait := o.wait
+	if strings.EqualFold(current.Status, "stopped") {
+		if !o.start {
+			return remote.Target{}, fmt.Errorf("%s is stopped; use --start to start it", vm.Name)
+		}
+		if err := checkCanStart(cfg, vm, actual); err != nil {
+			return remote.Target{}, fmt.Errorf("%s: cannot start: %w", vm.Name, err)
+		}
+		fmt.Fprintf(os.Stderr, "[>] %s: starting...\n", vm.Name)
+		if err := runVMForAction(vm, fleet.ActionStart); err != nil {
+			return remote.Target{}, fmt.Errorf("%s: start failed: %w", vm.Name, err)
+		}
+		if wait == 0 {
+			wait = defaultStartWait
+		}
+	}
+
+	deadline := time.Now().Add(wait)
+	for current.IPAddress == nil || *current.IPAddress == "" {
+		if !time.Now().Before(deadline) {
+			if wait == 0 {
+				return remote.Target{}, fmt.Errorf("%s has no IP address yet; use --wait", vm.Name)
+			}
+			return remote.Target{}, fmt.Errorf("%s has no IP address after %s", vm.Name, wait)
+		}
+		time.Sleep(pollInterval)
+		actual, err := listVMsViaCLI()
+		if err != nil {
+			return remote.Target{}, fmt.Errorf("cannot list VMs via lume CLI: %w", err)
+		}
+		current, _ = lumeVM(actual, vm.Name)
+	}
+
+	target := sshTarget(vm, *current.IPAddress)
+	if wait > 0 {
+		if err := remote.WaitReachable(target, max(time.Until(deadline), 0)); err != nil {
+			return remote.Target{}, err
+		}
+	}
+	return target, nil
+}
+
+// lumeVM returns the VM with the given name from `lume ls` output.
+func lumeVM(actual []lume.VM, name string) (lume.VM, bool) {
+	for _, vm := range actual {
+		if vm.Name == name {
+			return vm, true
+		}
+	}
+	return lume.VM{}, false
+}
@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hoalong/lume-fleet/fleet"
+	"github.com/hoalong/lume-fleet/remote"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cpFilter    vmFilter
+	cpConnect   connectOptions
+	cpRecursive bool
+)
+
+// copyPlan is a parsed cp command line.
+type copyPlan struct {
+	upload bool
+	// vm is the VM named in the remote operands, or "" for the VMs selected
+	// by the filter flags (":path").
+	vm string
+	// local holds the sources of an upload or the destination of a download;
+	// remote holds the destination of an upload or the sources of a download.
+	local  []string
+	remote []string
+}
+
+// parseCopyOperand splits "vm:path" and ":path" operands. Operands without a
+// colon, or with a slash before it, are local paths.
+func parseCopyOperand(s string) (vm, path string, isRemote bool) {
+	i := strings.Index(s, ":")
+	if i < 0 || strings.Contains(s[:i], "/") {
+		return "", s, false
+	}
+	return s[:i], s[i+1:], true
+}
+
+// planCopy works out the direction of a copy from its operands.
+func planCopy(args []string) (copyPlan, error) {
+	if len(args) < 2 {
+		return copyPlan{}, errors.New("need a source and a destination")
+	}
+	sources, dest := args[:len(args)-1], args[len(args)-1]
+
+	if vm, path, ok := parseCopyOperand(dest); ok {
+		for _, src := range sources {
+			if _, _, remote := parseCopyOperand(src); remote {
+				return copyPlan{}, errors.New("copying between VMs is not supported")
+			}
+		}
+		return copyPlan{upload: true, vm: vm, local: sources, remote: []string{path}}, nil
+	}
+
+	plan := copyPlan{local: []string{dest}}
+	for i, src := range sources {
+		vm, path, ok := parseCopyOperand(src)
+		if !ok {
+			return copyPlan{}, errors.New("one side of the copy must be on a VM (vm:path, or :path for the selected VMs)")
+		}
+		if i > 0 && vm != plan.vm {
+			return copyPlan{}, errors.New("all sources must be on the same VM")
+		}
+		plan.vm = vm
+		plan.remote = append(plan.remote, path)
+	}
+	return plan, nil
+}
+
+// checkSelection makes sure the VMs to copy to or from are named exactly
+// once: in vm:path operands, or with --tag or --selector for :path, so that
+// a bare :path never selects the whole fleet.
+func (p copyPlan) checkSelection(f vmFilter) error {
+	selecting := len(f.tags) > 0 || f.selector != ""
+	switch {
+	case p.vm != "" && selecting:
+		return errors.New("--tag and --selector only apply to :path operands")
+	case p.vm == "" && !selecting:
+		return errors.New(":path needs --tag or --selector to select VMs; use vm:path for a single VM")
+	}
+	return nil
+}
+
+var cpCmd = &cobra.Command{
+	Use:   "cp [-r] <source> ... <destination>",
+	Short: "Copy files to or from VMs with scp",
+	Long: `Copy files between this machine and VMs with scp, using the VM's IP address
+from lume and its ssh settings. Remote paths are written vm:path. Use :path
+with --tag or --selector to copy to or from every selected VM; downloads
+then go into one subdirectory per VM.`,
+	Example: `  lume-fleet cp build.tar.gz dev-main:/tmp/
+  lume-fleet cp dev-main:/var/log/install.log .
+  lume-fleet cp -r --tag ci ./scripts :/tmp/
+  lume-fleet cp --tag ci :/var/log/system.log ./logs`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		plan, err := planCopy(args)
+		if err != nil {
+			return err
+		}
+		if err := plan.checkSelection(cpFilter); err != nil {
+			return err
+		}
+
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		resolved, err := cfg.Resolve()
+		if err != nil {
+			return err
+		}
+
+		var vms []fleet.ResolvedVM
+		if plan.vm != "" {
+			vm, err := findVM(resolved, plan.vm)
+			if err != nil {
+				return err
+			}
+			vms = []fleet.ResolvedVM{vm}
+		} else if vms, err = cpFilter.apply(resolved, nil); err != nil {
+			return err
+		}
+		if len(vms) == 0 {
+			fmt.Println("No VMs match the given filters.")
+			return nil
+		}
+
+		cmd.SilenceUsage = true
+		failures := 0
+		for _, vm := range vms {
+			target, err := cpConnect.connect(cfg, vm)
+			if err == nil {
+				err = runCopy(plan, target)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "[x] %s: %v\n", vm.Name, err)
+				failures++
+				continue
+			}
+			fmt.Printf("[+] %s: copied\n", vm.Name)
+		}
+		if failures > 0 {
+			return fmt.Errorf("%d VM(s) failed", failures)
+		}
+		return nil
+	},
+}
+
+// runCopy runs the copy for one VM. Downloads from a selection of VMs go
+// into a subdirectory per VM so that files with the same name do not
+// overwrite each other.
+func runCopy(plan copyPlan, target remote.Target) error {
+	if plan.upload {
+		return remote.Upload(target, cpRecursive, plan.local, plan.remote[0], os.Stdout, os.Stderr)
+	}
+	dest := plan.local[0]
+	if plan.vm == "" {
+		dest = filepath.Join(dest, target.Name)
+		if err := os.MkdirAll(dest, 0o755); err != nil {
+			return err
+		}
+	}
+	return remote.Download(target, cpRecursive, plan.remote, dest, os.Stdout, os.Stderr)
+}
+
+func init() {
+	cpFilter.register(cpCmd)
+	cpConnect.register(cpCmd)
+	cpCmd.Flags().BoolVarP(&cpRecursive, "recursive", "r", false, "copy directories recursively")
+	rootCmd.AddCommand(cpCmd)
+}
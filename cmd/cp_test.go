@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPlanCopy(t *testing.T) {
+	tests := []struct {
+		args []string
+		want copyPlan
+	}{
+		{
+			args: []string{"a.txt", "b", "dev-main:/tmp/"},
+			want: copyPlan{upload: true, vm: "dev-main", local: []string{"a.txt", "b"}, remote: []string{"/tmp/"}},
+		},
+		{
+			args: []string{"dev-main:/var/log/x.log", "dev-main:y", "."},
+			want: copyPlan{vm: "dev-main", local: []string{"."}, remote: []string{"/var/log/x.log", "y"}},
+		},
+		{
+			args: []string{"./build:1.tar", ":/tmp/"},
+			want: copyPlan{upload: true, local: []string{"./build:1.tar"}, remote: []string{"/tmp/"}},
+		},
+		{
+			args: []string{":/var/log/system.log", "logs"},
+			want: copyPlan{local: []string{"logs"}, remote: []string{"/var/log/system.log"}},
+		},
+	}
+	for _, tt := range tests {
+		got, err := planCopy(tt.args)
+		if err != nil {
+			t.Fatalf("planCopy(%v) returned error: %v", tt.args, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("planCopy(%v) = %+v, want %+v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestPlanCopyErrors(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"a:/x", "b:/y"}, "between VMs"},
+		{[]string{"x", "a:/y", "b:/z"}, "between VMs"},
+		{[]string{"a:/x", "b:/y", "."}, "same VM"},
+		{[]string{"x", "y"}, "must be on a VM"},
+		{[]string{"a:/x"}, "destination"},
+	}
+	for _, tt := range tests {
+		_, err := planCopy(tt.args)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("planCopy(%v) error = %v, want %q", tt.args, err, tt.want)
+		}
+	}
+}
+
+func TestCopyPlanCheckSelection(t *testing.T) {
+	tests := []struct {
+		args   []string
+		filter vmFilter
+		want   string
+	}{
+		{[]string{"x", "dev:/tmp/"}, vmFilter{}, ""},
+		{[]string{"x", ":/tmp/"}, vmFilter{tags: []string{"ci"}}, ""},
+		{[]string{":/var/log/system.log", "."}, vmFilter{selector: "role=builder"}, ""},
+		{[]string{"x", ":/tmp/"}, vmFilter{}, "needs --tag or --selector"},
+		{[]string{":/var/log/system.log", "."}, vmFilter{}, "needs --tag or --selector"},
+		{[]string{"x", "dev:/tmp/"}, vmFilter{tags: []string{"ci"}}, "only apply to :path"},
+	}
+	for _, tt := range tests {
+		plan, err := planCopy(tt.args)
+		if err != nil {
+			t.Fatalf("planCopy(%v) returned error: %v", tt.args, err)
+		}
+		err = plan.checkSelection(tt.filter)
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("checkSelection(%v, %+v) returned error: %v", tt.args, tt.filter, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("checkSelection(%v, %+v) error = %v, want %q", tt.args, tt.filter, err, tt.want)
+		}
+	}
+}
@@ -10,15 +10,15 @@ import (
 )
 
 var (
-	destroyTag   string
-	destroyForce bool
+	destroyFilter vmFilter
+	destroyForce  bool
 )
 
 var destroyCmd = &cobra.Command{
 	Use:   "destroy [vm1 vm2 ...]",
 	Short: "Delete VMs entirely",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, err := fleet.LoadConfig(cfgFile)
+		cfg, err := loadConfig()
 		if err != nil {
 			return err
 		}
@@ -28,8 +28,10 @@ var destroyCmd = &cobra.Command{
 			return err
 		}
 
-		resolved = fleet.FilterByNames(resolved, args)
-		resolved = fleet.FilterByTag(resolved, destroyTag)
+		resolved, err = destroyFilter.apply(resolved, args)
+		if err != nil {
+			return err
+		}
 		if len(resolved) == 0 {
 			fmt.Println("No VMs match the given filters.")
 			return nil
@@ -84,7 +86,7 @@ var destroyCmd = &cobra.Command{
 }
 
 func init() {
-	destroyCmd.Flags().StringVar(&destroyTag, "tag", "", "filter VMs by tag")
+	destroyFilter.register(destroyCmd)
 	destroyCmd.Flags().BoolVar(&destroyForce, "force", false, "skip confirmation")
 	rootCmd.AddCommand(destroyCmd)
 }
@@ -9,13 +9,13 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var downTag string
+var downFilter vmFilter
 
 var downCmd = &cobra.Command{
 	Use:   "down [vm1 vm2 ...]",
 	Short: "Stop running VMs",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, err := fleet.LoadConfig(cfgFile)
+		cfg, err := loadConfig()
 		if err != nil {
 			return err
 		}
@@ -25,8 +25,10 @@ var downCmd = &cobra.Command{
 			return err
 		}
 
-		resolved = fleet.FilterByNames(resolved, args)
-		resolved = fleet.FilterByTag(resolved, downTag)
+		resolved, err = downFilter.apply(resolved, args)
+		if err != nil {
+			return err
+		}
 		if len(resolved) == 0 {
 			fmt.Println("No VMs match the given filters.")
 			return nil
@@ -62,6 +64,6 @@ var downCmd = &cobra.Command{
 }
 
 func init() {
-	downCmd.Flags().StringVar(&downTag, "tag", "", "filter VMs by tag")
+	downFilter.register(downCmd)
 	rootCmd.AddCommand(downCmd)
 }
@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/hoalong/lume-fleet/lume"
+	"github.com/hoalong/lume-fleet/remote"
+	"github.com/spf13/cobra"
+)
+
+var (
+	execFilter   vmFilter
+	execParallel int
+	execCollect  bool
+	execJSON     bool
+)
+
+// execResult is the outcome of exec on one VM, as printed by --json.
+type execResult struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"` // ok, failed, error or skipped
+	ExitCode *int   `json:"exitCode,omitempty"`
+	Error    string `json:"error,omitempty"`
+	Stdout   string `json:"stdout,omitempty"`
+	Stderr   string `json:"stderr,omitempty"`
+}
+
+var execCmd = &cobra.Command{
+	Use:   "exec [vm1 vm2 ...] -- <command> [args ...]",
+	Short: "Run a command over SSH on running VMs",
+	Long: `Run a command over SSH on every selected running VM concurrently. Output is
+streamed with each line prefixed by the VM name, or collected per VM with
+--collect. A summary of exit codes follows; the command fails if any VM did.`,
+	Example: `  lume-fleet exec --tag ci -- rm -rf ~/Library/Caches/build
+  lume-fleet exec --json ci-runner-* -- df -h /`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dash := cmd.ArgsLenAtDash()
+		if dash < 0 || dash == len(args) {
+			return errors.New("missing command; use: lume-fleet exec [vm ...] -- <command>")
+		}
+		names, command := args[:dash], args[dash:]
+
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		resolved, err := cfg.Resolve()
+		if err != nil {
+			return err
+		}
+		resolved, err = execFilter.apply(resolved, names)
+		if err != nil {
+			return err
+		}
+		if len(resolved) == 0 {
+			fmt.Println("No VMs match the given filters.")
+			return nil
+		}
+
+		actual, err := lume.ListVMsViaCLI()
+		if err != nil {
+			return fmt.Errorf("cannot list VMs via lume CLI: %w", err)
+		}
+
+		results := make([]execResult, len(resolved))
+		var targets []remote.Target
+		var slots []int
+		width := 0
+		for i, vm := range resolved {
+			results[i] = execResult{Name: vm.Name, Status: "skipped"}
+			current, ok := lumeVM(actual, vm.Name)
+			switch {
+			case !ok:
+				results[i].Error = "not created"
+			case !strings.EqualFold(current.Status, "running"):
+				results[i].Error = "not running"
+			case current.IPAddress == nil || *current.IPAddress == "":
+				results[i].Error = "no IP address"
+			default:
+				targets = append(targets, sshTarget(vm, *current.IPAddress))
+				slots = append(slots, i)
+				width = max(width, len(vm.Name))
+			}
+		}
+
+		var output func(remote.Target) (io.Writer, io.Writer)
+		if !execCollect && !execJSON {
+			var mu sync.Mutex
+			output = func(t remote.Target) (io.Writer, io.Writer) {
+				prefix := fmt.Sprintf("%-*s | ", width, t.Name)
+				return remote.NewPrefixWriter(os.Stdout, prefix, &mu), remote.NewPrefixWriter(os.Stderr, prefix, &mu)
+			}
+		}
+
+		for j, r := range remote.RunAll(targets, command, execParallel, output) {
+			res := &results[slots[j]]
+			res.Stdout, res.Stderr = string(r.Stdout), string(r.Stderr)
+			switch {
+			case r.Err != nil:
+				res.Status, res.Error = "error", r.Err.Error()
+			case r.ExitCode == 0:
+				res.Status = "ok"
+			default:
+				res.Status = "failed"
+			}
+			if r.Err == nil {
+				res.ExitCode = &r.ExitCode
+			}
+		}
+
+		failures := 0
+		for _, res := range results {
+			if res.Status == "failed" || res.Status == "error" {
+				failures++
+			}
+		}
+		cmd.SilenceUsage = true
+
+		if execJSON {
+			data, err := json.MarshalIndent(results, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+		} else {
+			printExecResults(results)
+		}
+		if failures > 0 {
+			return fmt.Errorf("%d VM(s) failed", failures)
+		}
+		return nil
+	},
+}
+
+func init() {
+	execFilter.register(execCmd)
+	execCmd.Flags().IntVarP(&execParallel, "parallel", "p", 4, "maximum number of VMs to run the command on at once")
+	execCmd.Flags().BoolVar(&execCollect, "collect", false, "print each VM's output together instead of streaming prefixed lines")
+	execCmd.Flags().BoolVar(&execJSON, "json", false, "output results, including collected output, as JSON")
+	rootCmd.AddCommand(execCmd)
+}
+
+// printExecResults prints collected output, if any, followed by a summary
+// line per VM.
+func printExecResults(results []execResult) {
+	if execCollect {
+		for _, res := range results {
+			if res.Status == "skipped" {
+				continue
+			}
+			fmt.Printf("=== %s ===\n", res.Name)
+			for _, out := range []struct {
+				w    io.Writer
+				text string
+			}{{os.Stdout, res.Stdout}, {os.Stderr, res.Stderr}} {
+				if out.text != "" && !strings.HasSuffix(out.text, "\n") {
+					out.text += "\n"
+				}
+				fmt.Fprint(out.w, out.text)
+			}
+		}
+		fmt.Println()
+	}
+
+	counts := map[string]int{}
+	for _, res := range results {
+		counts[res.Status]++
+		switch res.Status {
+		case "ok":
+			fmt.Printf("[+] %s: exit 0\n", res.Name)
+		case "failed":
+			fmt.Fprintf(os.Stderr, "[x] %s: exit %d\n", res.Name, *res.ExitCode)
+		case "error":
+			fmt.Fprintf(os.Stderr, "[x] %s: ssh failed: %s\n", res.Name, res.Error)
+		case "skipped":
+			fmt.Fprintf(os.Stderr, "[!] %s: skipped — %s\n", res.Name, res.Error)
+		}
+	}
+	fmt.Printf("%d ok, %d failed, %d skipped\n", counts["ok"], counts["failed"]+counts["error"], counts["skipped"])
+}
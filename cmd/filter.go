@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"github.com/hoalong/lume-fleet/fleet"
+	"github.com/spf13/cobra"
+)
+
+// vmFilter holds the VM selection flags shared by commands.
+type vmFilter struct {
+	tags     []string
+	selector string
+}
+
+func (f *vmFilter) register(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVar(&f.tags, "tag", nil, "filter VMs by tag (repeatable; matches any)")
+	cmd.Flags().StringVarP(&f.selector, "selector", "l", "", `filter VMs by selector, e.g. "role=builder,team!=android"`)
+}
+
+// apply narrows vms to those matching names (exact or glob), any of the
+// tags, and the selector.
+func (f *vmFilter) apply(vms []fleet.ResolvedVM, names []string) ([]fleet.ResolvedVM, error) {
+	vms = fleet.FilterByNames(vms, names)
+	vms = fleet.FilterByTags(vms, f.tags)
+	if f.selector == "" {
+		return vms, nil
+	}
+	sel, err := fleet.ParseSelector(f.selector)
+	if err != nil {
+		return nil, err
+	}
+	return fleet.FilterBySelector(vms, sel), nil
+}
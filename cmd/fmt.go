@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/hoalong/lume-fleet/fleet"
+	"github.com/spf13/cobra"
+)
+
+var (
+	fmtCheck bool
+	fmtSort  bool
+)
+
+var fmtCmd = &cobra.Command{
+	Use:   "fmt",
+	Short: "Rewrite config files in canonical form",
+	Long: `Rewrite every --config file in canonical form: two-space indentation,
+normalized size strings (8gb becomes 8GB), and no VM values that only repeat
+the defaults of the same file or the files it includes. Comments and VM
+order are kept unless --sort is given.
+
+With --check nothing is written; the command lists files that need
+formatting and fails if there are any, for use in pre-commit hooks.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := loadConfig(); err != nil {
+			return err
+		}
+
+		unformatted := 0
+		for _, path := range cfgFiles {
+			doc, err := fleet.OpenDocument(path)
+			if err != nil {
+				return err
+			}
+			doc.Format(fmtSort)
+
+			changed, err := doc.Changed()
+			if err != nil {
+				return err
+			}
+			if !changed {
+				continue
+			}
+			if fmtCheck {
+				fmt.Println(path)
+				unformatted++
+				continue
+			}
+			if err := doc.Save(); err != nil {
+				return err
+			}
+			fmt.Printf("[+] %s: formatted\n", path)
+		}
+
+		if unformatted > 0 {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("%d file(s) need formatting (run lume-fleet fmt)", unformatted)
+		}
+		return nil
+	},
+}
+
+func init() {
+	fmtCmd.Flags().BoolVar(&fmtCheck, "check", false, "list unformatted files and exit non-zero instead of writing")
+	fmtCmd.Flags().BoolVar(&fmtSort, "sort", false, "sort VMs by name")
+	rootCmd.AddCommand(fmtCmd)
+}
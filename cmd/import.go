@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/hoalong/lume-fleet/fleet"
+	"github.com/hoalong/lume-fleet/lume"
+	"github.com/spf13/cobra"
+)
+
+var importDryRun bool
+
+var importCmd = &cobra.Command{
+	Use:   "import [vm1 vm2 ...]",
+	Short: "Add existing Lume VMs to fleet.yml",
+	Long: `Reads the VMs known to lume and writes them to the config file.
+
+A new config factors values shared by most VMs into defaults. An existing
+config is merged into: VMs it already defines are left alone, and new VMs
+only list values that differ from its defaults. Comments and ordering in
+the existing file are preserved.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		actual, err := lume.ListVMsViaCLI()
+		if err != nil {
+			return fmt.Errorf("cannot list VMs via lume CLI: %w", err)
+		}
+		actual = filterLumeVMs(actual, args)
+		if len(actual) == 0 {
+			fmt.Println("No VMs to import.")
+			return nil
+		}
+
+		target := cfgFiles[0]
+		doc, err := fleet.OpenDocument(target)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			doc, err = fleet.NewDocument(target, fleet.ImportVMs(actual, nil))
+			if err != nil {
+				return err
+			}
+			for _, vm := range actual {
+				fmt.Printf("[+] %s: imported\n", vm.Name)
+			}
+		case err != nil:
+			return err
+		default:
+			added, skipped, err := importInto(doc, actual)
+			if err != nil {
+				return err
+			}
+			for _, name := range skipped {
+				fmt.Printf("[ ] %s: already in %s\n", name, target)
+			}
+			for _, name := range added {
+				fmt.Printf("[+] %s: imported\n", name)
+			}
+		}
+
+		if importDryRun {
+			data, err := doc.Bytes()
+			if err != nil {
+				return err
+			}
+			_, err = os.Stdout.Write(data)
+			return err
+		}
+		if err := doc.Save(); err != nil {
+			return err
+		}
+		fmt.Printf("Wrote %s\n", target)
+		return nil
+	},
+}
+
+func init() {
+	importCmd.Flags().BoolVar(&importDryRun, "dry-run", false, "print the resulting config instead of writing it")
+	rootCmd.AddCommand(importCmd)
+}
+
+// importInto adds the VMs in actual that doc does not define yet, listing
+// only values that differ from its defaults. VMs doc already defines are
+// left as they are and returned as skipped, even when lume reports
+// different resources for them.
+func importInto(doc *fleet.Document, actual []lume.VM) (added, skipped []string, err error) {
+	defaults, err := doc.Defaults()
+	if err != nil {
+		return nil, nil, err
+	}
+	imported := fleet.ImportVMs(actual, &defaults)
+	for _, vm := range actual {
+		if doc.HasVM(vm.Name) {
+			skipped = append(skipped, vm.Name)
+			continue
+		}
+		if err := doc.AddVM(vm.Name, imported.VMs[vm.Name]); err != nil {
+			return nil, nil, err
+		}
+		added = append(added, vm.Name)
+	}
+	return added, skipped, nil
+}
+
+// filterLumeVMs keeps the VMs whose names match one of names (exact or
+// glob), sorted by name. No names keeps every VM.
+func filterLumeVMs(vms []lume.VM, names []string) []lume.VM {
+	var result []lume.VM
+	for _, vm := range vms {
+		if len(names) == 0 || slices.ContainsFunc(names, func(n string) bool {
+			ok, _ := path.Match(n, vm.Name)
+			return ok || n == vm.Name
+		}) {
+			result = append(result, vm)
+		}
+	}
+	slices.SortFunc(result, func(a, b lume.VM) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return result
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/hoalong/lume-fleet/fleet"
+	"github.com/hoalong/lume-fleet/lume"
+)
+
+func TestImportIntoKeepsExistingVMs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fleet.yml")
+	if err := os.WriteFile(path, []byte(`version: 2
+defaults:
+  os: macos
+  cpu: 4
+  memory: 8GB
+vms:
+  dev:
+    cpu: 8 # pinned
+`), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	doc, err := fleet.OpenDocument(path)
+	if err != nil {
+		t.Fatalf("OpenDocument() returned error: %v", err)
+	}
+
+	const gb = 1 << 30
+	actual := []lume.VM{
+		{Name: "ci", OS: "macOS", CPUCount: 4, MemorySize: 16 * gb, DiskSize: &lume.DiskSize{Total: 50 * gb}},
+		{Name: "dev", OS: "macOS", CPUCount: 2, MemorySize: 4 * gb, DiskSize: &lume.DiskSize{Total: 50 * gb}},
+	}
+	added, skipped, err := importInto(doc, actual)
+	if err != nil {
+		t.Fatalf("importInto() returned error: %v", err)
+	}
+	if !slices.Equal(added, []string{"ci"}) || !slices.Equal(skipped, []string{"dev"}) {
+		t.Fatalf("importInto() = added %v, skipped %v, want added [ci], skipped [dev]", added, skipped)
+	}
+
+	data, err := doc.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes() returned error: %v", err)
+	}
+	got := string(data)
+	if !strings.Contains(got, "  dev:\n    cpu: 8 # pinned\n") {
+		t.Errorf("existing VM was changed:\n%s", got)
+	}
+	ci := got[strings.Index(got, "  ci:"):]
+	if !strings.Contains(ci, "memory: 16GB") || strings.Contains(ci, "cpu:") || strings.Contains(ci, "os:") {
+		t.Errorf("ci should only list values that differ from defaults:\n%s", got)
+	}
+}
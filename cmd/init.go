@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var initForce bool
+
+const starterConfig = `# lume-fleet configuration
+# See the lume-fleet README for every supported field.
+
+version: 2
+
+# Values inherited by every VM. Any VM field can be set here.
+defaults:
+  os: macos          # macos or linux
+  cpu: 4
+  memory: 8GB        # MB, GB or TB
+  disk-size: 50GB
+  unattended: tahoe  # macOS unattended setup preset
+
+# Named groups of fields that VMs can pull in with "extends".
+# profiles:
+#   big-disk:
+#     disk-size: 200GB
+
+vms:
+  # A macOS development VM. Uncomment shared-dirs to share a project folder.
+  dev-main:
+    cpu: 8
+    memory: 16GB
+    image: latest    # macOS IPSW path or latest
+    # shared-dirs:
+    #   - path: ~/Projects
+    tags: [dev]
+
+  # A Linux VM installed from an ISO on first start.
+  # linux-1:
+  #   os: linux
+  #   memory: 4GB
+  #   image: ~/Downloads/ubuntu-25.10-desktop-arm64.iso
+  #   tags: [ci]
+`
+
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Write a commented starter fleet.yml",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		target := cfgFiles[0]
+		flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+		if initForce {
+			flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+		}
+
+		f, err := os.OpenFile(target, flags, 0o644)
+		if os.IsExist(err) {
+			return fmt.Errorf("%s already exists (use --force to overwrite, or import to add existing VMs)", target)
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := f.WriteString(starterConfig); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+
+		fmt.Printf("Wrote %s\n", target)
+		return nil
+	},
+}
+
+func init() {
+	initCmd.Flags().BoolVar(&initForce, "force", false, "overwrite an existing config file")
+	rootCmd.AddCommand(initCmd)
+}
@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hoalong/lume-fleet/fleet"
+)
+
+func TestStarterConfigResolvesInAnEmptyHome(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "fleet.yml")
+	if err := os.WriteFile(path, []byte(starterConfig), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfg, err := fleet.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig(starter) returned error: %v", err)
+	}
+	if _, err := cfg.Resolve(); err != nil {
+		t.Fatalf("Resolve(starter) returned error: %v", err)
+	}
+	if problems := cfg.Validate(); len(problems) > 0 {
+		t.Fatalf("Validate(starter) = %v, want no problems", problems)
+	}
+}
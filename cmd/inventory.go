@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hoalong/lume-fleet/fleet"
+	"github.com/hoalong/lume-fleet/lume"
+	"github.com/hoalong/lume-fleet/remote"
+	"github.com/spf13/cobra"
+)
+
+// managedSSHConfig is the SSH config file kept up to date by
+// `inventory --install` and `up`, relative to the home directory.
+const managedSSHConfig = ".ssh/config.d/lume-fleet"
+
+const managedSSHConfigHeader = `# Managed by lume-fleet; changes are overwritten by "lume-fleet up".
+# Load it by adding this line at the top of ~/.ssh/config:
+#   Include config.d/*
+
+`
+
+var (
+	inventoryFilter  vmFilter
+	inventoryFormat  string
+	inventoryInstall bool
+	inventoryList    bool
+	inventoryHost    string
+)
+
+var inventoryCmd = &cobra.Command{
+	Use:   "inventory",
+	Short: "Print an SSH config or Ansible inventory for the fleet",
+	Long: `Print a host entry for every VM with the IP address lume reports, its ssh
+settings, and its tags and OS as groups. VMs without an IP address are left
+out of Ansible inventories.
+
+Formats: ssh-config, ansible-ini, ansible-yaml, and json (the format of an
+Ansible dynamic inventory script; --list and --host are accepted for that).
+
+--install writes the SSH config of the whole fleet to ~/` + managedSSHConfig + `,
+which "lume-fleet up" then keeps up to date.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		resolved, err := cfg.Resolve()
+		if err != nil {
+			return err
+		}
+		actual, err := lume.ListVMsViaCLI()
+		if err != nil {
+			return fmt.Errorf("cannot list VMs via lume CLI: %w", err)
+		}
+
+		if inventoryInstall {
+			path, err := writeManagedSSHConfig(inventoryHosts(resolved, actual), actual)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("[+] wrote %s\n", path)
+			if !sshConfigIncludes() {
+				fmt.Fprintf(os.Stderr, "[!] add `Include config.d/*` at the top of ~/.ssh/config to use it\n")
+			}
+			return nil
+		}
+
+		resolved, err = inventoryFilter.apply(resolved, nil)
+		if err != nil {
+			return err
+		}
+		hosts := inventoryHosts(resolved, actual)
+
+		var data []byte
+		switch {
+		case inventoryHost != "":
+			data, err = remote.HostVarsJSON(hosts, inventoryHost)
+		case inventoryFormat == "ssh-config":
+			data = remote.SSHConfig(hosts)
+		case inventoryFormat == "ansible-ini":
+			data = remote.AnsibleINI(hosts)
+		case inventoryFormat == "ansible-yaml":
+			data, err = remote.AnsibleYAML(hosts)
+		case inventoryFormat == "json":
+			data, err = remote.AnsibleJSON(hosts)
+		default:
+			return fmt.Errorf("unknown format %q (use ssh-config, ansible-ini, ansible-yaml or json)", inventoryFormat)
+		}
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(data)
+		return err
+	},
+}
+
+func init() {
+	inventoryFilter.register(inventoryCmd)
+	inventoryCmd.Flags().StringVarP(&inventoryFormat, "format", "f", "ssh-config", "output format: ssh-config, ansible-ini, ansible-yaml or json")
+	inventoryCmd.Flags().BoolVar(&inventoryInstall, "install", false, "write the SSH config of the whole fleet to ~/"+managedSSHConfig)
+	inventoryCmd.Flags().BoolVar(&inventoryList, "list", false, "print the json inventory (for Ansible dynamic inventory scripts)")
+	inventoryCmd.Flags().StringVar(&inventoryHost, "host", "", "print the variables of one host (for Ansible dynamic inventory scripts)")
+	inventoryCmd.Flags().MarkHidden("list")
+	inventoryCmd.Flags().MarkHidden("host")
+	inventoryCmd.PreRun = func(cmd *cobra.Command, args []string) {
+		if inventoryList {
+			inventoryFormat = "json"
+		}
+	}
+	rootCmd.AddCommand(inventoryCmd)
+}
+
+// inventoryHosts returns an inventory host per VM, with the IP address lume
+// reports for it and its tags and OS as groups.
+func inventoryHosts(vms []fleet.ResolvedVM, actual []lume.VM) []remote.Host {
+	hosts := make([]remote.Host, 0, len(vms))
+	for _, vm := range vms {
+		ip := ""
+		if current, ok := lumeVM(actual, vm.Name); ok && current.IPAddress != nil {
+			ip = *current.IPAddress
+		}
+		groups := append([]string(nil), vm.Tags...)
+		groups = append(groups, strings.ToLower(vm.OS))
+		hosts = append(hosts, remote.Host{Target: sshTarget(vm, ip), Groups: groups})
+	}
+	return hosts
+}
+
+func managedSSHConfigPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, managedSSHConfig), nil
+}
+
+// writeManagedSSHConfig writes hosts to the managed SSH config file. VMs
+// that lume reports as starting or running but without an IP address yet
+// keep the address from the previous file; VMs in any other state are
+// commented out, like VMs without an address.
+func writeManagedSSHConfig(hosts []remote.Host, actual []lume.VM) (string, error) {
+	path, err := managedSSHConfigPath()
+	if err != nil {
+		return "", err
+	}
+	var previous map[string]string
+	if old, err := os.ReadFile(path); err == nil {
+		previous = remote.SSHConfigAddresses(old)
+	}
+	for i := range hosts {
+		current, _ := lumeVM(actual, hosts[i].Name)
+		switch {
+		case !strings.EqualFold(current.Status, "running") && !strings.EqualFold(current.Status, "starting"):
+			hosts[i].Host = ""
+		case hosts[i].Host == "":
+			hosts[i].Host = previous[hosts[i].Name]
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return "", err
+	}
+	data := append([]byte(managedSSHConfigHeader), remote.SSHConfig(hosts)...)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	return path, nil
+}
+
+// refreshManagedSSHConfig rewrites the managed SSH config after VMs have
+// changed, if it was installed.
+func refreshManagedSSHConfig(cfg *fleet.FleetConfig) error {
+	path, err := managedSSHConfigPath()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	resolved, err := cfg.Resolve()
+	if err != nil {
+		return err
+	}
+	actual, err := lume.ListVMsViaCLI()
+	if err != nil {
+		return fmt.Errorf("cannot list VMs via lume CLI: %w", err)
+	}
+	if _, err := writeManagedSSHConfig(inventoryHosts(resolved, actual), actual); err != nil {
+		return err
+	}
+	fmt.Printf("[+] updated %s\n", path)
+	return nil
+}
+
+// sshConfigIncludes reports whether ~/.ssh/config appears to include the
+// config.d directory.
+func sshConfigIncludes() bool {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return false
+	}
+	data, err := os.ReadFile(filepath.Join(home, ".ssh", "config"))
+	return err == nil && strings.Contains(string(data), "config.d")
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hoalong/lume-fleet/fleet"
+	"github.com/hoalong/lume-fleet/lume"
+)
+
+func TestInventoryHosts(t *testing.T) {
+	ip := "192.168.64.5"
+	vms := []fleet.ResolvedVM{
+		{Name: "dev", OS: "macOS", Tags: []string{"dev"}, SSH: fleet.SSHSpec{User: "lume"}},
+		{Name: "ci", OS: "linux"},
+	}
+	actual := []lume.VM{{Name: "dev", Status: "running", IPAddress: &ip}}
+
+	hosts := inventoryHosts(vms, actual)
+
+	if hosts[0].Host != ip || hosts[0].User != "lume" || !reflect.DeepEqual(hosts[0].Groups, []string{"dev", "macos"}) {
+		t.Errorf("dev host = %+v", hosts[0])
+	}
+	if hosts[1].Host != "" || !reflect.DeepEqual(hosts[1].Groups, []string{"linux"}) {
+		t.Errorf("ci host = %+v", hosts[1])
+	}
+}
+
+func TestWriteManagedSSHConfigKeepsPreviousAddresses(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	ip, ciIP := "192.168.64.5", "192.168.64.6"
+	vms := []fleet.ResolvedVM{{Name: "dev", OS: "macos"}, {Name: "ci", OS: "macos"}}
+
+	running := []lume.VM{{Name: "dev", Status: "running", IPAddress: &ip}, {Name: "ci", Status: "running", IPAddress: &ciIP}}
+	if _, err := writeManagedSSHConfig(inventoryHosts(vms, running), running); err != nil {
+		t.Fatalf("writeManagedSSHConfig() returned error: %v", err)
+	}
+	// dev is booting and has no address yet; ci was stopped.
+	later := []lume.VM{{Name: "dev", Status: "starting"}, {Name: "ci", Status: "stopped", IPAddress: &ciIP}}
+	path, err := writeManagedSSHConfig(inventoryHosts(vms, later), later)
+	if err != nil {
+		t.Fatalf("writeManagedSSHConfig() returned error: %v", err)
+	}
+
+	if want := filepath.Join(home, managedSSHConfig); path != want {
+		t.Fatalf("path = %s, want %s", path, want)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "# Managed by lume-fleet") || !strings.Contains(string(data), "HostName "+ip) {
+		t.Fatalf("managed SSH config =\n%s", data)
+	}
+	if strings.Contains(string(data), "HostName "+ciIP) || !strings.Contains(string(data), "# ci: no IP address") {
+		t.Fatalf("managed SSH config keeps the stopped VM:\n%s", data)
+	}
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/hoalong/lume-fleet/fleet"
+	"github.com/spf13/cobra"
+)
+
+var removeCmd = &cobra.Command{
+	Use:   "remove <vm>",
+	Short: "Remove a VM from fleet.yml (the VM itself is left alone)",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		doc, err := fleet.OpenDocument(cfgFiles[0])
+		if err != nil {
+			return err
+		}
+		if err := doc.RemoveVM(name); err != nil {
+			return err
+		}
+		if err := saveDocument(doc); err != nil {
+			return err
+		}
+		fmt.Printf("[-] %s: removed from %s (use destroy to delete the VM)\n", name, doc.Path())
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(removeCmd)
+}
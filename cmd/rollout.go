@@ -0,0 +1,233 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hoalong/lume-fleet/fleet"
+	"github.com/hoalong/lume-fleet/remote"
+	"github.com/spf13/cobra"
+)
+
+var (
+	rolloutFilter  vmFilter
+	rolloutBatch   int
+	rolloutDrain   string
+	rolloutRestart bool
+	rolloutTimeout time.Duration
+)
+
+var rolloutCmd = &cobra.Command{
+	Use:   "rollout [vm1 vm2 ...] -- <command> [args ...]",
+	Short: "Run a maintenance command on running VMs batch by batch",
+	Long: `Run a maintenance command over SSH on the selected running VMs, a batch at a
+time. For each VM in a batch, rollout runs the --drain command, then the
+maintenance command, optionally restarts the VM, and waits until it accepts
+SSH connections again. The rollout stops after the first batch in which any
+VM failed.`,
+	Example: `  lume-fleet rollout --tag ci --batch 2 --drain "sudo launchctl stop runner" --restart -- ./patch.sh`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dash := cmd.ArgsLenAtDash()
+		if dash < 0 || dash == len(args) {
+			return errors.New("missing command; use: lume-fleet rollout [vm ...] -- <command>")
+		}
+		names, command := args[:dash], args[dash:]
+
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		resolved, err := cfg.Resolve()
+		if err != nil {
+			return err
+		}
+		resolved, err = rolloutFilter.apply(resolved, names)
+		if err != nil {
+			return err
+		}
+		if len(resolved) == 0 {
+			fmt.Println("No VMs match the given filters.")
+			return nil
+		}
+
+		actual, err := listVMsViaCLI()
+		if err != nil {
+			return fmt.Errorf("cannot list VMs via lume CLI: %w", err)
+		}
+		var vms []fleet.ResolvedVM
+		for _, vm := range resolved {
+			if current, ok := lumeVM(actual, vm.Name); ok && strings.EqualFold(current.Status, "running") {
+				vms = append(vms, vm)
+			} else {
+				fmt.Fprintf(os.Stderr, "[!] %s: skipped — not running\n", vm.Name)
+			}
+		}
+
+		cmd.SilenceUsage = true
+		return runRollout(cfg, vms, command)
+	},
+}
+
+func init() {
+	rolloutFilter.register(rolloutCmd)
+	rolloutCmd.Flags().IntVar(&rolloutBatch, "batch", 1, "number of VMs to process at once")
+	rolloutCmd.Flags().StringVar(&rolloutDrain, "drain", "", "command run over SSH on each VM before the maintenance command")
+	rolloutCmd.Flags().BoolVar(&rolloutRestart, "restart", false, "restart each VM (lume stop, lume run) after the maintenance command")
+	rolloutCmd.Flags().DurationVar(&rolloutTimeout, "timeout", 5*time.Minute, "how long to wait for a VM to become reachable again")
+	rootCmd.AddCommand(rolloutCmd)
+}
+
+// runRollout processes vms batch by batch and stops after the first batch
+// in which any VM failed.
+func runRollout(cfg *fleet.FleetConfig, vms []fleet.ResolvedVM, command []string) error {
+	batches := rolloutBatches(vms, rolloutBatch)
+	for i, batch := range batches {
+		var batchNames []string
+		for _, vm := range batch {
+			batchNames = append(batchNames, vm.Name)
+		}
+		fmt.Printf("[>] batch %d/%d: %s\n", i+1, len(batches), strings.Join(batchNames, ", "))
+
+		failed := runRolloutBatch(cfg, batch, command)
+		if len(failed) > 0 {
+			var remaining []string
+			for _, b := range batches[i+1:] {
+				for _, vm := range b {
+					remaining = append(remaining, vm.Name)
+				}
+			}
+			if len(remaining) > 0 {
+				fmt.Fprintf(os.Stderr, "[!] rollout stopped; not processed: %s\n", strings.Join(remaining, ", "))
+			}
+			return fmt.Errorf("batch %d failed: %s", i+1, strings.Join(failed, ", "))
+		}
+	}
+	fmt.Printf("[+] rollout complete: %d VM(s)\n", len(vms))
+	return nil
+}
+
+// rolloutBatches splits vms into batches of size n, keeping their order.
+func rolloutBatches(vms []fleet.ResolvedVM, n int) [][]fleet.ResolvedVM {
+	n = max(n, 1)
+	var batches [][]fleet.ResolvedVM
+	for len(vms) > 0 {
+		k := min(n, len(vms))
+		batches = append(batches, vms[:k])
+		vms = vms[k:]
+	}
+	return batches
+}
+
+// runRolloutBatch processes the VMs of a batch concurrently and returns the
+// names of those that failed.
+func runRolloutBatch(cfg *fleet.FleetConfig, batch []fleet.ResolvedVM, command []string) []string {
+	width := 0
+	for _, vm := range batch {
+		width = max(width, len(vm.Name))
+	}
+	var mu sync.Mutex
+	errs := make([]error, len(batch))
+	var wg sync.WaitGroup
+	for i, vm := range batch {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			prefix := fmt.Sprintf("%-*s | ", width, vm.Name)
+			stdout := remote.NewPrefixWriter(os.Stdout, prefix, &mu)
+			stderr := remote.NewPrefixWriter(os.Stderr, prefix, &mu)
+			errs[i] = rolloutVM(cfg, vm, command, stdout, stderr)
+			stdout.Flush()
+			stderr.Flush()
+		}()
+	}
+	wg.Wait()
+
+	var failed []string
+	for i, err := range errs {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[x] %s: %v\n", batch[i].Name, err)
+			failed = append(failed, batch[i].Name)
+			continue
+		}
+		fmt.Printf("[+] %s: done\n", batch[i].Name)
+	}
+	return failed
+}
+
+// rolloutVM drains vm, runs command on it, restarts it if asked and waits
+// until it is reachable again.
+func rolloutVM(cfg *fleet.FleetConfig, vm fleet.ResolvedVM, command []string, stdout, stderr io.Writer) error {
+	target, err := connectOptions{wait: rolloutTimeout}.connect(cfg, vm)
+	if err != nil {
+		return err
+	}
+
+	if rolloutDrain != "" {
+		fmt.Fprintln(stdout, "[>] draining...")
+		if err := runRemote(target, []string{rolloutDrain}, stdout, stderr); err != nil {
+			return fmt.Errorf("drain: %w", err)
+		}
+	}
+
+	fmt.Fprintln(stdout, "[>] running command...")
+	if err := runRemote(target, command, stdout, stderr); err != nil {
+		return err
+	}
+
+	if rolloutRestart {
+		fmt.Fprintln(stdout, "[>] restarting...")
+		deadline := time.Now().Add(rolloutTimeout)
+		if err := stopVMViaCLI(vm.Name); err != nil {
+			return err
+		}
+		if err := runVMForAction(vm, fleet.ActionStart); err != nil {
+			return fmt.Errorf("start failed: %w", err)
+		}
+		// lume run returns before the VM is up, so lume ls may still report
+		// it stopped, or with the address it had before the restart.
+		if err := waitRunning(vm.Name, deadline); err != nil {
+			return err
+		}
+		if _, err := (connectOptions{wait: max(time.Until(deadline), time.Second)}).connect(cfg, vm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// waitRunning polls lume until the named VM is running with an IP address
+// or deadline passes.
+func waitRunning(name string, deadline time.Time) error {
+	for {
+		actual, err := listVMsViaCLI()
+		if err != nil {
+			return fmt.Errorf("cannot list VMs via lume CLI: %w", err)
+		}
+		current, _ := lumeVM(actual, name)
+		if strings.EqualFold(current.Status, "running") && current.IPAddress != nil && *current.IPAddress != "" {
+			return nil
+		}
+		if !time.Now().Before(deadline) {
+			return fmt.Errorf("not running with an IP address after %s", rolloutTimeout)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// runRemote runs command on target and turns a non-zero exit into an error.
+// Tests replace it.
+var runRemote = func(target remote.Target, command []string, stdout, stderr io.Writer) error {
+	code, err := remote.Run(target, command, stdout, stderr)
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		return fmt.Errorf("exit %d", code)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hoalong/lume-fleet/fleet"
+	"github.com/hoalong/lume-fleet/lume"
+	"github.com/hoalong/lume-fleet/remote"
+)
+
+func TestRolloutBatches(t *testing.T) {
+	var vms []fleet.ResolvedVM
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		vms = append(vms, fleet.ResolvedVM{Name: name})
+	}
+
+	tests := []struct {
+		n    int
+		want [][]string
+	}{
+		{2, [][]string{{"a", "b"}, {"c", "d"}, {"e"}}},
+		{0, [][]string{{"a"}, {"b"}, {"c"}, {"d"}, {"e"}}},
+		{10, [][]string{{"a", "b", "c", "d", "e"}}},
+	}
+	for _, tt := range tests {
+		var got [][]string
+		for _, batch := range rolloutBatches(vms, tt.n) {
+			var names []string
+			for _, vm := range batch {
+				names = append(names, vm.Name)
+			}
+			got = append(got, names)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("rolloutBatches(%d) = %v, want %v", tt.n, got, tt.want)
+		}
+	}
+}
+
+// fakeRollout replaces lume and ssh for rollout tests with VMs a and b,
+// running at 127.0.0.1 with an SSH port that accepts connections. fail
+// returns the error of a step ("drain", "command" or "restart") on a VM.
+// It returns the steps run, in order.
+func fakeRollout(t *testing.T, fail func(vm, step string) error) (*fleet.FleetConfig, []fleet.ResolvedVM, *[]string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	origList, origStop, origRun, origRemote, origPoll := listVMsViaCLI, stopVMViaCLI, runVMViaCLI, runRemote, pollInterval
+	origBatch, origDrain, origRestart, origTimeout := rolloutBatch, rolloutDrain, rolloutRestart, rolloutTimeout
+	t.Cleanup(func() {
+		listVMsViaCLI, stopVMViaCLI, runVMViaCLI, runRemote, pollInterval = origList, origStop, origRun, origRemote, origPoll
+		rolloutBatch, rolloutDrain, rolloutRestart, rolloutTimeout = origBatch, origDrain, origRestart, origTimeout
+	})
+	rolloutBatch, rolloutTimeout, pollInterval = 1, 5*time.Second, time.Millisecond
+
+	var mu sync.Mutex
+	var steps []string
+	status := map[string]string{"a": "running", "b": "running"}
+	step := func(vm, name string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		steps = append(steps, vm+":"+name)
+		return fail(vm, name)
+	}
+	listVMsViaCLI = func() ([]lume.VM, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		ip := "127.0.0.1"
+		var vms []lume.VM
+		for _, name := range []string{"a", "b"} {
+			vm := lume.VM{Name: name, Status: status[name]}
+			if status[name] == "running" {
+				vm.IPAddress = &ip
+			}
+			// A VM that was just started is reported stopped once more.
+			if status[name] == "booting" {
+				status[name] = "running"
+				vm.Status = "stopped"
+			}
+			vms = append(vms, vm)
+		}
+		return vms, nil
+	}
+	stopVMViaCLI = func(name string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		status[name] = "stopped"
+		return nil
+	}
+	runVMViaCLI = func(name string, _ []lume.SharedDirectory, _ string) error {
+		if err := step(name, "restart"); err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		status[name] = "booting"
+		return nil
+	}
+	runRemote = func(target remote.Target, command []string, _, _ io.Writer) error {
+		if command[0] == "drain" {
+			return step(target.Name, "drain")
+		}
+		return step(target.Name, "command")
+	}
+
+	vms := []fleet.ResolvedVM{
+		{Name: "a", SSH: fleet.SSHSpec{Port: port}},
+		{Name: "b", SSH: fleet.SSHSpec{Port: port}},
+	}
+	return &fleet.FleetConfig{}, vms, &steps
+}
+
+func TestRolloutRestartsAndWaitsForTheVM(t *testing.T) {
+	cfg, vms, steps := fakeRollout(t, func(vm, step string) error { return nil })
+	rolloutDrain, rolloutRestart = "drain", true
+
+	if err := runRollout(cfg, vms, []string{"./patch.sh"}); err != nil {
+		t.Fatalf("runRollout() returned error: %v", err)
+	}
+	want := "a:drain a:command a:restart b:drain b:command b:restart"
+	if got := strings.Join(*steps, " "); got != want {
+		t.Fatalf("steps = %s, want %s", got, want)
+	}
+}
+
+func TestRolloutStopsAfterAFailedBatch(t *testing.T) {
+	tests := []struct {
+		step    string
+		want    string
+		wantErr string
+	}{
+		{"drain", "a:drain", "batch 1 failed: a"},
+		{"command", "a:drain a:command", "batch 1 failed: a"},
+		{"restart", "a:drain a:command a:restart", "batch 1 failed: a"},
+	}
+	for _, tt := range tests {
+		cfg, vms, steps := fakeRollout(t, func(vm, step string) error {
+			if vm == "a" && step == tt.step {
+				return errors.New("boom")
+			}
+			return nil
+		})
+		rolloutDrain, rolloutRestart = "drain", true
+
+		err := runRollout(cfg, vms, []string{"./patch.sh"})
+		if err == nil || err.Error() != tt.wantErr {
+			t.Errorf("%s failure: runRollout() error = %v, want %q", tt.step, err, tt.wantErr)
+		}
+		if got := strings.Join(*steps, " "); got != tt.want {
+			t.Errorf("%s failure: steps = %s, want %s", tt.step, got, tt.want)
+		}
+	}
+}
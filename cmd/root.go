@@ -4,10 +4,14 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/hoalong/lume-fleet/fleet"
 	"github.com/spf13/cobra"
 )
 
-var cfgFile string
+var (
+	cfgFiles []string
+	cfgEnv   string
+)
 
 var rootCmd = &cobra.Command{
 	Use:   "lume-fleet",
@@ -16,7 +20,26 @@ var rootCmd = &cobra.Command{
 }
 
 func init() {
-	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "fleet.yml", "path to fleet config file")
+	rootCmd.PersistentFlags().StringArrayVar(&cfgFiles, "config", []string{"fleet.yml"}, "path to fleet config file (repeatable; later files are merged on top)")
+	rootCmd.PersistentFlags().StringVar(&cfgEnv, "env", "", "layer fleet.<env>.yml on top of the config")
+}
+
+// loadOptions returns the config files selected by the global flags.
+func loadOptions() fleet.LoadOptions {
+	return fleet.LoadOptions{Paths: cfgFiles, Env: cfgEnv}
+}
+
+// loadConfig loads the config selected by the global flags and prints any
+// load warnings to stderr.
+func loadConfig() (*fleet.FleetConfig, error) {
+	cfg, err := fleet.Load(loadOptions())
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range cfg.Warnings {
+		fmt.Fprintf(os.Stderr, "[!] %s\n", w)
+	}
+	return cfg, nil
 }
 
 func Execute() {
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/hoalong/lume-fleet/fleet"
+	"github.com/spf13/cobra"
+)
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema for fleet.yml",
+	Long: `Print a JSON Schema describing fleet.yml. Point a YAML language server at it
+for completion and inline validation in editors:
+
+  lume-fleet schema > fleet.schema.json
+  # yaml-language-server: $schema=./fleet.schema.json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		data, err := fleet.Schema()
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(data)
+		return err
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(schemaCmd)
+}
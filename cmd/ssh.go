@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"github.com/hoalong/lume-fleet/remote"
+	"github.com/spf13/cobra"
+)
+
+var sshConnect connectOptions
+
+var sshCmd = &cobra.Command{
+	Use:   "ssh <vm> [command ...]",
+	Short: "Open an SSH session to a VM",
+	Long: `Look up the VM's IP address from lume and run the system ssh with the
+user, identity file, port and options from the VM's ssh block. Arguments
+after the VM name are run as a remote command.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		resolved, err := cfg.Resolve()
+		if err != nil {
+			return err
+		}
+		vm, err := findVM(resolved, args[0])
+		if err != nil {
+			return err
+		}
+
+		target, err := sshConnect.connect(cfg, vm)
+		if err != nil {
+			return err
+		}
+		return remote.Exec(target, args[1:]...)
+	},
+}
+
+func init() {
+	sshConnect.register(sshCmd)
+	// Flags after the VM name belong to the remote command.
+	sshCmd.Flags().SetInterspersed(false)
+	rootCmd.AddCommand(sshCmd)
+}
@@ -1,10 +1,16 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/charmbracelet/x/term"
 	"github.com/hoalong/lume-fleet/fleet"
 	"github.com/hoalong/lume-fleet/lume"
 	"github.com/hoalong/lume-fleet/ui"
@@ -12,15 +18,22 @@ import (
 )
 
 var (
-	statusTag  string
-	statusJSON bool
+	statusFilter vmFilter
+	statusJSON   bool
+	statusWide   bool
+
+	statusWatch    bool
+	statusInterval time.Duration
 )
 
+// watchHistory is how many transitions status --watch keeps on screen.
+const watchHistory = 10
+
 var statusCmd = &cobra.Command{
-	Use:   "status",
+	Use:   "status [vm1 vm2 ...]",
 	Short: "Show fleet VM status",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, err := fleet.LoadConfig(cfgFile)
+		cfg, err := loadConfig()
 		if err != nil {
 			return err
 		}
@@ -30,12 +43,26 @@ var statusCmd = &cobra.Command{
 			return err
 		}
 
-		resolved = fleet.FilterByTag(resolved, statusTag)
+		resolved, err = statusFilter.apply(resolved, args)
+		if err != nil {
+			return err
+		}
 		if len(resolved) == 0 {
 			fmt.Println("No VMs match the given filters.")
 			return nil
 		}
 
+		if statusWatch {
+			if statusJSON {
+				return fmt.Errorf("--watch cannot be combined with --json")
+			}
+			if statusInterval <= 0 {
+				return fmt.Errorf("--interval must be positive, got %s", statusInterval)
+			}
+			cmd.SilenceUsage = true
+			return watchStatus(cfg, resolved)
+		}
+
 		actual, err := lume.ListVMsViaCLI()
 		if err != nil {
 			return fmt.Errorf("cannot list VMs via lume CLI: %w", err)
@@ -45,16 +72,92 @@ var statusCmd = &cobra.Command{
 			return printJSON(resolved, actual)
 		}
 
-		rows := ui.BuildStatusRows(resolved, actual)
-		macosRunning := fleet.CountRunningMacOS(actual)
-		fmt.Println(ui.RenderStatusTable(rows, macosRunning))
+		printStatus(cfg, ui.BuildStatusRows(resolved, actual), actual)
 		return nil
 	},
 }
 
+// printStatus prints the status table, and warnings about VMs that no
+// longer match their size to stderr.
+func printStatus(cfg *fleet.FleetConfig, rows []ui.StatusRow, actual []lume.VM) {
+	fmt.Println(ui.RenderStatusTable(rows, fleet.CountRunningMacOS(actual), cfg.MacOSLimit(), statusWide))
+	warnSizeDrift(rows)
+}
+
+// warnSizeDrift warns on stderr about existing VMs whose resources differ
+// from what their size resolves to.
+func warnSizeDrift(rows []ui.StatusRow) {
+	for _, row := range rows {
+		if len(row.SizeDrift) > 0 {
+			fmt.Fprintf(os.Stderr, "[!] %s no longer matches size %s: %s\n", row.Name, row.Size, strings.Join(row.SizeDrift, ", "))
+		}
+	}
+}
+
+// watchStatus polls lume every --interval until interrupted. On a terminal
+// it redraws the table in place with the recent transitions below it;
+// otherwise it prints the initial state and then one line per transition.
+func watchStatus(cfg *fleet.FleetConfig, resolved []fleet.ResolvedVM) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	tty := term.IsTerminal(os.Stdout.Fd())
+	ticker := time.NewTicker(statusInterval)
+	defer ticker.Stop()
+
+	var (
+		prev    []ui.StatusRow
+		history []ui.Transition
+	)
+	for {
+		now := time.Now()
+		actual, err := lume.ListVMsViaCLI()
+		switch {
+		case err != nil && tty:
+			fmt.Print("\x1b[H\x1b[2J")
+			fmt.Printf("[x] %s  cannot list VMs via lume CLI: %v\n", now.Format(time.TimeOnly), err)
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "[x] %s cannot list VMs via lume CLI: %v\n", now.Format(time.RFC3339), err)
+		default:
+			rows := ui.BuildStatusRows(resolved, actual)
+			transitions := ui.StatusTransitions(prev, rows, now)
+			if tty {
+				history = append(history, transitions...)
+				if len(history) > watchHistory {
+					history = history[len(history)-watchHistory:]
+				}
+				fmt.Print("\x1b[H\x1b[2J")
+				printStatus(cfg, rows, actual)
+				fmt.Println()
+				fmt.Println(ui.RenderTransitions(history, now))
+				fmt.Printf("\nEvery %s, last update %s. Press Ctrl-C to quit.\n", statusInterval, now.Format(time.TimeOnly))
+			} else if prev == nil {
+				for _, r := range rows {
+					fmt.Printf("%s %s: %s %s\n", now.Format(time.RFC3339), r.Name, r.State, r.IP)
+				}
+				warnSizeDrift(rows)
+			} else {
+				for _, t := range transitions {
+					fmt.Printf("%s %s: %s\n", t.Time.Format(time.RFC3339), t.Name, t.Change)
+				}
+			}
+			prev = rows
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
 func init() {
-	statusCmd.Flags().StringVar(&statusTag, "tag", "", "filter VMs by tag")
+	statusFilter.register(statusCmd)
 	statusCmd.Flags().BoolVar(&statusJSON, "json", false, "output as JSON")
+	statusCmd.Flags().BoolVarP(&statusWide, "wide", "w", false, "show more columns (VNC URL)")
+	statusCmd.Flags().BoolVar(&statusWatch, "watch", false, "keep polling and redraw the table, highlighting state changes")
+	statusCmd.Flags().DurationVar(&statusInterval, "interval", 2*time.Second, "how often --watch polls lume")
 	rootCmd.AddCommand(statusCmd)
 }
 
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/term"
+	"github.com/hoalong/lume-fleet/fleet"
+	"github.com/hoalong/lume-fleet/lume"
+	"github.com/hoalong/lume-fleet/tui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	tuiFilter   vmFilter
+	tuiInterval time.Duration
+)
+
+var tuiCmd = &cobra.Command{
+	Use:   "tui [vm1 vm2 ...]",
+	Short: "Open an interactive dashboard of the fleet",
+	Long: `Show the fleet in a full-screen dashboard that polls lume every --interval.
+Select a VM with the arrow keys to see its resolved spec next to its actual
+state and a log of its operations, and start (s), stop (x), destroy (d,
+confirmed with y) or ssh into it (enter).`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		resolved, err := cfg.Resolve()
+		if err != nil {
+			return err
+		}
+		resolved, err = tuiFilter.apply(resolved, args)
+		if err != nil {
+			return err
+		}
+		if len(resolved) == 0 {
+			fmt.Println("No VMs match the given filters.")
+			return nil
+		}
+		if tuiInterval <= 0 {
+			return fmt.Errorf("--interval must be positive, got %s", tuiInterval)
+		}
+		if !term.IsTerminal(os.Stdout.Fd()) {
+			return errors.New("tui needs a terminal; use `lume-fleet status --watch` instead")
+		}
+
+		cmd.SilenceUsage = true
+		model := tui.New(resolved, tuiActions(cfg), tuiInterval)
+		_, err = tea.NewProgram(model, tea.WithAltScreen()).Run()
+		return err
+	},
+}
+
+func init() {
+	tuiFilter.register(tuiCmd)
+	tuiCmd.Flags().DurationVar(&tuiInterval, "interval", 2*time.Second, "how often to poll lume")
+	rootCmd.AddCommand(tuiCmd)
+}
+
+// tuiActions runs the dashboard operations with the same checks as the
+// corresponding commands.
+func tuiActions(cfg *fleet.FleetConfig) tui.Actions {
+	// existing returns the lume state of vm and the VMs lume knows about.
+	existing := func(vm fleet.ResolvedVM) (lume.VM, []lume.VM, error) {
+		actual, err := lume.ListVMsViaCLI()
+		if err != nil {
+			return lume.VM{}, nil, fmt.Errorf("cannot list VMs via lume CLI: %w", err)
+		}
+		current, ok := lumeVM(actual, vm.Name)
+		if !ok {
+			return lume.VM{}, nil, fmt.Errorf("%s is not created; run `lume-fleet up %s`", vm.Name, vm.Name)
+		}
+		return current, actual, nil
+	}
+
+	return tui.Actions{
+		List: lume.ListVMsViaCLI,
+		Start: func(vm fleet.ResolvedVM) error {
+			current, actual, err := existing(vm)
+			if err != nil {
+				return err
+			}
+			if !strings.EqualFold(current.Status, "stopped") {
+				return fmt.Errorf("%s is %s", vm.Name, current.Status)
+			}
+			if err := checkCanStart(cfg, vm, actual); err != nil {
+				return err
+			}
+			return runVMForAction(vm, fleet.ActionStart)
+		},
+		Stop: func(vm fleet.ResolvedVM) error {
+			current, _, err := existing(vm)
+			if err != nil {
+				return err
+			}
+			if !strings.EqualFold(current.Status, "running") {
+				return fmt.Errorf("%s is not running", vm.Name)
+			}
+			return stopVMViaCLI(vm.Name)
+		},
+		Destroy: func(vm fleet.ResolvedVM) error {
+			current, _, err := existing(vm)
+			if err != nil {
+				return err
+			}
+			// Stop running VMs before deleting, like destroy.
+			if current.Status == "running" {
+				if err := stopVMViaCLI(vm.Name); err != nil {
+					return err
+				}
+			}
+			return lume.DeleteVM(vm.Name)
+		},
+		SSH: func(vm fleet.ResolvedVM) (*exec.Cmd, error) {
+			target, err := connectOptions{}.connect(cfg, vm)
+			if err != nil {
+				return nil, err
+			}
+			return exec.Command("ssh", target.SSHArgs()...), nil
+		},
+	}
+}
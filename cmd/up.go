@@ -10,54 +10,83 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var upTag string
 var (
-	runVMViaCLI = func(name, sharedDir, mountISO string) error {
-		return lume.RunVMViaCLI(name, sharedDir, mountISO)
+	upFilter  vmFilter
+	upPreempt bool
+)
+
+var (
+	runVMViaCLI = func(name string, sharedDirs []lume.SharedDirectory, mountISO string) error {
+		return lume.RunVMViaCLI(name, sharedDirs, mountISO)
 	}
+	stopVMViaCLI   = lume.StopVMViaCLI
+	listVMsViaCLI  = lume.ListVMsViaCLI
+	createVMViaCLI = lume.CreateVMViaCLI
 )
 
 var upCmd = &cobra.Command{
 	Use:   "up [vm1 vm2 ...]",
 	Short: "Create and start VMs defined in fleet.yml",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, err := fleet.LoadConfig(cfgFile)
+		cfg, err := loadConfig()
 		if err != nil {
 			return err
 		}
 
-		resolved, err := cfg.Resolve()
+		all, err := cfg.Resolve()
 		if err != nil {
 			return err
 		}
 
-		resolved = fleet.FilterByNames(resolved, args)
-		resolved = fleet.FilterByTag(resolved, upTag)
+		resolved, err := upFilter.apply(all, args)
+		if err != nil {
+			return err
+		}
 		if len(resolved) == 0 {
 			fmt.Println("No VMs match the given filters.")
 			return nil
 		}
 
-		actual, err := lume.ListVMsViaCLI()
+		actual, err := listVMsViaCLI()
 		if err != nil {
 			return fmt.Errorf("cannot list VMs via lume CLI: %w", err)
 		}
 
-		actions := fleet.PlanUp(resolved, actual)
-		macosRunning := fleet.CountRunningMacOS(actual)
-		failures := 0
+		limits, warning, err := cfg.UpLimits(all, actual, upPreempt)
+		if err != nil {
+			return err
+		}
+		if warning != "" {
+			fmt.Fprintf(os.Stderr, "[!] %s\n", warning)
+		}
 
-		for _, a := range actions {
+		failures := 0
+		// blocked holds the VMs whose preemption victim could not be stopped.
+		blocked := map[string]string{}
+		for _, a := range fleet.PlanUp(resolved, actual, limits) {
+			if victim, ok := blocked[a.VM.Name]; ok {
+				fmt.Fprintf(os.Stderr, "[!] %s: skipped — could not stop %s to free a macOS slot\n", a.VM.Name, victim)
+				failures++
+				continue
+			}
 			switch a.Type {
 			case fleet.ActionNoop:
 				fmt.Printf("[ ] %s: already running\n", a.VM.Name)
 
-			case fleet.ActionStart:
-				if strings.EqualFold(a.VM.OS, "macos") && macosRunning >= 2 {
-					fmt.Fprintf(os.Stderr, "[!] %s: skipped — macOS 2-VM concurrent limit reached\n", a.VM.Name)
-					failures++
+			case fleet.ActionSkip:
+				fmt.Fprintf(os.Stderr, "[!] %s: skipped — %s\n", a.VM.Name, a.Reason)
+				failures++
+
+			case fleet.ActionStop:
+				fmt.Printf("[>] %s: stopping to %s...\n", a.VM.Name, a.Reason)
+				if err := stopVMViaCLI(a.VM.Name); err != nil {
+					fmt.Fprintf(os.Stderr, "[x] %s: stop failed: %v\n", a.VM.Name, err)
+					blocked[a.For] = a.VM.Name
 					continue
 				}
+				fmt.Printf("[-] %s: stopped (preempted)\n", a.VM.Name)
+
+			case fleet.ActionStart:
 				fmt.Printf("[>] %s: starting...\n", a.VM.Name)
 				err := runVMForAction(a.VM, fleet.ActionStart)
 				if err != nil {
@@ -65,14 +94,11 @@ var upCmd = &cobra.Command{
 					failures++
 					continue
 				}
-				if strings.EqualFold(a.VM.OS, "macos") {
-					macosRunning++
-				}
 				fmt.Printf("[+] %s: running\n", a.VM.Name)
 
 			case fleet.ActionCreate:
-				if strings.EqualFold(a.VM.OS, "macos") && macosRunning >= 2 {
-					fmt.Fprintf(os.Stderr, "[!] %s: skipped — macOS 2-VM concurrent limit reached\n", a.VM.Name)
+				if err := fleet.CheckVNCPort(a.VM, all); err != nil {
+					fmt.Fprintf(os.Stderr, "[!] %s: skipped — %v\n", a.VM.Name, err)
 					failures++
 					continue
 				}
@@ -80,7 +106,7 @@ var upCmd = &cobra.Command{
 
 				createReq := buildCreateRequest(a.VM)
 
-				if err := lume.CreateVMViaCLI(createReq); err != nil {
+				if err := createVMViaCLI(createReq); err != nil {
 					fmt.Fprintf(os.Stderr, "[x] %s: create failed: %v\n", a.VM.Name, err)
 					failures++
 					continue
@@ -93,13 +119,14 @@ var upCmd = &cobra.Command{
 					failures++
 					continue
 				}
-				if strings.EqualFold(a.VM.OS, "macos") {
-					macosRunning++
-				}
 				fmt.Printf("[+] %s: running\n", a.VM.Name)
 			}
 		}
 
+		if err := refreshManagedSSHConfig(cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "[!] cannot update SSH config: %v\n", err)
+		}
+
 		if failures > 0 {
 			return fmt.Errorf("%d VM(s) failed", failures)
 		}
@@ -108,7 +135,8 @@ var upCmd = &cobra.Command{
 }
 
 func init() {
-	upCmd.Flags().StringVar(&upTag, "tag", "", "filter VMs by tag")
+	upFilter.register(upCmd)
+	upCmd.Flags().BoolVar(&upPreempt, "preempt", false, "stop lower-priority running macOS VMs of the fleet to stay within the macOS limit")
 	rootCmd.AddCommand(upCmd)
 }
 
@@ -134,9 +162,20 @@ func buildCreateRequest(vm fleet.ResolvedVM) lume.CreateRequest {
 
 func buildRunRequest(vm fleet.ResolvedVM) lume.RunRequest {
 	return lume.RunRequest{
-		NoDisplay: true,
-		SharedDir: vm.SharedDir,
+		NoDisplay:         true,
+		SharedDirectories: sharedDirectories(vm),
+	}
+}
+
+func sharedDirectories(vm fleet.ResolvedVM) []lume.SharedDirectory {
+	var dirs []lume.SharedDirectory
+	for _, d := range vm.SharedDirs {
+		dirs = append(dirs, lume.SharedDirectory{
+			HostPath: d.Path,
+			ReadOnly: d.ReadOnly,
+		})
 	}
+	return dirs
 }
 
 func shouldUseISOMountOnCreate(vm fleet.ResolvedVM, actionType fleet.ActionType) bool {
@@ -148,5 +187,5 @@ func runVMForAction(vm fleet.ResolvedVM, actionType fleet.ActionType) error {
 	if shouldUseISOMountOnCreate(vm, actionType) {
 		mountISO = vm.Image
 	}
-	return runVMViaCLI(vm.Name, vm.SharedDir, mountISO)
+	return runVMViaCLI(vm.Name, sharedDirectories(vm), mountISO)
 }
@@ -2,10 +2,13 @@ package cmd
 
 import (
 	"encoding/json"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/hoalong/lume-fleet/fleet"
+	"github.com/hoalong/lume-fleet/lume"
 )
 
 func TestBuildCreateRequestIncludesVNCPort(t *testing.T) {
@@ -56,8 +59,8 @@ func TestBuildCreateRequestDefaultsMacOSIPSWToLatest(t *testing.T) {
 
 func TestBuildRunRequestDoesNotIncludeVNCPort(t *testing.T) {
 	vm := fleet.ResolvedVM{
-		SharedDir: "/tmp/share",
-		VNCPort:   5999,
+		SharedDirs: []fleet.SharedDir{{Path: "/tmp/share"}, {Path: "/tmp/data", ReadOnly: true}},
+		VNCPort:    5999,
 	}
 
 	req := buildRunRequest(vm)
@@ -71,8 +74,14 @@ func TestBuildRunRequestDoesNotIncludeVNCPort(t *testing.T) {
 		t.Fatalf("run request unexpectedly includes vncPort: %s", payload)
 	}
 
-	if req.SharedDir != "/tmp/share" {
-		t.Fatalf("run request sharedDir = %q, want /tmp/share", req.SharedDir)
+	if len(req.SharedDirectories) != 2 {
+		t.Fatalf("run request sharedDirectories = %v, want 2 entries", req.SharedDirectories)
+	}
+	if req.SharedDirectories[0].HostPath != "/tmp/share" || req.SharedDirectories[0].ReadOnly {
+		t.Fatalf("run request sharedDirectories[0] = %+v, want read-write /tmp/share", req.SharedDirectories[0])
+	}
+	if req.SharedDirectories[1].HostPath != "/tmp/data" || !req.SharedDirectories[1].ReadOnly {
+		t.Fatalf("run request sharedDirectories[1] = %+v, want read-only /tmp/data", req.SharedDirectories[1])
 	}
 }
 
@@ -105,7 +114,7 @@ func TestRunVMForActionUsesCLIOnlyForLinuxCreateWithImage(t *testing.T) {
 	var cliCalled bool
 	var cliMount string
 
-	runVMViaCLI = func(name, sharedDir, mountISO string) error {
+	runVMViaCLI = func(name string, sharedDirs []lume.SharedDirectory, mountISO string) error {
 		cliCalled = true
 		cliMount = mountISO
 		return nil
@@ -137,7 +146,7 @@ func TestRunVMForActionUsesCLIForStartWithoutMount(t *testing.T) {
 	var cliCalled bool
 	var cliMount string
 
-	runVMViaCLI = func(name, sharedDir, mountISO string) error {
+	runVMViaCLI = func(name string, sharedDirs []lume.SharedDirectory, mountISO string) error {
 		cliCalled = true
 		cliMount = mountISO
 		return nil
@@ -159,3 +168,42 @@ func TestRunVMForActionUsesCLIForStartWithoutMount(t *testing.T) {
 		t.Fatalf("expected no mount on start, got %q", cliMount)
 	}
 }
+
+func TestUpRefusesToCreateWithADuplicateVNCPort(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "fleet.yml")
+	config := `host:
+  cpu: 64
+  memory: 256GB
+vms:
+  builder:
+    os: linux
+    vnc-port: 5901
+    autostart: false
+  dev:
+    os: linux
+    vnc-port: 5901
+`
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	origFiles, origList, origCreate := cfgFiles, listVMsViaCLI, createVMViaCLI
+	t.Cleanup(func() { cfgFiles, listVMsViaCLI, createVMViaCLI = origFiles, origList, origCreate })
+	cfgFiles = []string{path}
+	listVMsViaCLI = func() ([]lume.VM, error) {
+		return []lume.VM{{Name: "builder", OS: "linux", Status: "stopped", CPUCount: 2, MemorySize: 4 << 30}}, nil
+	}
+	var created []string
+	createVMViaCLI = func(req lume.CreateRequest) error {
+		created = append(created, req.Name)
+		return nil
+	}
+
+	err := upCmd.RunE(upCmd, nil)
+	if err == nil || !strings.Contains(err.Error(), "1 VM(s) failed") {
+		t.Fatalf("up error = %v, want dev to fail", err)
+	}
+	if len(created) != 0 {
+		t.Fatalf("up created %v, want nothing", created)
+	}
+}
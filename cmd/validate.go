@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hoalong/lume-fleet/fleet"
+	"github.com/spf13/cobra"
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check fleet.yml and report every problem found",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		problems, err := fleet.ValidateConfig(loadOptions())
+		if err != nil {
+			return err
+		}
+
+		if len(problems) == 0 {
+			fmt.Printf("%s: OK\n", strings.Join(cfgFiles, ", "))
+			return nil
+		}
+
+		for _, p := range problems {
+			fmt.Fprintln(os.Stderr, p)
+		}
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%d problem(s) found", len(problems))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+}
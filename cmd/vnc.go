@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/hoalong/lume-fleet/lume"
+	"github.com/spf13/cobra"
+)
+
+var (
+	vncOpen   bool
+	vncViewer string
+)
+
+var vncCmd = &cobra.Command{
+	Use:   "vnc <vm>",
+	Short: "Show or open the VNC URL of a VM",
+	Long: `Print the VNC URL lume reports for a running VM. With --open, run a VNC
+viewer with the URL: --viewer, host.vnc-viewer from the config, or the
+system opener (open on macOS, xdg-open elsewhere). A {url} token in the
+viewer command is replaced with the URL, which is appended otherwise.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		resolved, err := cfg.Resolve()
+		if err != nil {
+			return err
+		}
+		vm, err := findVM(resolved, args[0])
+		if err != nil {
+			return err
+		}
+
+		actual, err := lume.ListVMsViaCLI()
+		if err != nil {
+			return fmt.Errorf("cannot list VMs via lume CLI: %w", err)
+		}
+		current, ok := lumeVM(actual, vm.Name)
+		if !ok {
+			return fmt.Errorf("%s is not created; run `lume-fleet up %s`", vm.Name, vm.Name)
+		}
+		if current.VNCUrl == nil || *current.VNCUrl == "" {
+			return fmt.Errorf("%s has no VNC URL (status: %s)", vm.Name, current.Status)
+		}
+		url := *current.VNCUrl
+
+		if !vncOpen {
+			fmt.Println(url)
+			return nil
+		}
+		viewer := vncViewer
+		if viewer == "" {
+			viewer = cfg.Host.VNCViewer
+		}
+		command := viewerCommand(viewer, url)
+		fmt.Fprintf(os.Stderr, "[>] %s: opening %s\n", vm.Name, url)
+		c := exec.Command("sh", "-c", command)
+		c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+		if err := c.Run(); err != nil {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("VNC viewer %q failed: %w", command, err)
+		}
+		return nil
+	},
+}
+
+func init() {
+	vncCmd.Flags().BoolVar(&vncOpen, "open", false, "open the URL in a VNC viewer")
+	vncCmd.Flags().StringVar(&vncViewer, "viewer", "", "viewer command for --open (default: host.vnc-viewer, then open or xdg-open)")
+	rootCmd.AddCommand(vncCmd)
+}
+
+// viewerCommand returns the shell command that opens url with viewer. The
+// URL replaces a {url} token or is appended, quoted for the shell.
+func viewerCommand(viewer, url string) string {
+	if viewer == "" {
+		viewer = "xdg-open"
+		if runtime.GOOS == "darwin" {
+			viewer = "open"
+		}
+	}
+	quoted := "'" + strings.ReplaceAll(url, "'", `'\''`) + "'"
+	if strings.Contains(viewer, "{url}") {
+		return strings.ReplaceAll(viewer, "{url}", quoted)
+	}
+	return viewer + " " + quoted
+}
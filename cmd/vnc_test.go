@@ -0,0 +1,18 @@
+package cmd
+
+import "testing"
+
+func TestViewerCommand(t *testing.T) {
+	tests := []struct {
+		viewer, url, want string
+	}{
+		{"vncviewer", "vnc://:pw@127.0.0.1:5901", "vncviewer 'vnc://:pw@127.0.0.1:5901'"},
+		{`open -a "Screen Sharing" {url}`, "vnc://host:5900", `open -a "Screen Sharing" 'vnc://host:5900'`},
+		{"viewer {url} --fullscreen", "vnc://:it's@host:5900", `viewer 'vnc://:it'\''s@host:5900' --fullscreen`},
+	}
+	for _, tt := range tests {
+		if got := viewerCommand(tt.viewer, tt.url); got != tt.want {
+			t.Fatalf("viewerCommand(%q, %q) = %q, want %q", tt.viewer, tt.url, got, tt.want)
+		}
+	}
+}
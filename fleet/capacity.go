@@ -0,0 +1,240 @@
+package fleet
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hoalong/lume-fleet/lume"
+	"gopkg.in/yaml.v3"
+)
+
+// HostSpec describes the host VMs run on: the resources they are scheduled
+// against, where unset fields are detected, and the tools used to reach them.
+type HostSpec struct {
+	CPU    int    `yaml:"cpu,omitempty"`
+	Memory string `yaml:"memory,omitempty"`
+	// MacOSLimit is how many macOS VMs may run at once (default 2).
+	MacOSLimit int `yaml:"macos-limit,omitempty"`
+	// Overcommit scales the host resources into the budget VMs may use,
+	// e.g. 1.5 lets VMs claim 50% more CPUs and memory than the host has.
+	Overcommit float64 `yaml:"overcommit,omitempty"`
+	// VNCViewer is the command `lume-fleet vnc --open` runs. A {url} token
+	// is replaced with the VNC URL, which is appended otherwise.
+	VNCViewer string `yaml:"vnc-viewer,omitempty"`
+
+	// present records where each key was set, for problem positions.
+	present map[string]Position
+}
+
+// UnmarshalYAML decodes a host block and records where its keys were set.
+func (h *HostSpec) UnmarshalYAML(node *yaml.Node) error {
+	type plain HostSpec
+	if err := node.Decode((*plain)(h)); err != nil {
+		return err
+	}
+	h.present = presentKeys(node)
+	return nil
+}
+
+// Capacity is the CPU and memory budget available to VMs. A zero field is
+// not limited.
+type Capacity struct {
+	CPU      int
+	MemoryMB int64
+}
+
+func (c Capacity) String() string {
+	return fmt.Sprintf("%d CPU, %s", c.CPU, FormatSize(c.MemoryMB))
+}
+
+// detectHost returns the CPU count and memory of this machine. It is a
+// variable so tests can replace it.
+var detectHost = func() (cpu int, memoryMB int64, err error) {
+	cpu = runtime.NumCPU()
+	switch runtime.GOOS {
+	case "darwin":
+		out, err := exec.Command("sysctl", "-n", "hw.ncpu", "hw.memsize").Output()
+		if err != nil {
+			return cpu, 0, fmt.Errorf("sysctl: %w", err)
+		}
+		fields := strings.Fields(string(out))
+		if len(fields) != 2 {
+			return cpu, 0, fmt.Errorf("sysctl: unexpected output %q", out)
+		}
+		if n, err := strconv.Atoi(fields[0]); err == nil {
+			cpu = n
+		}
+		memsize, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return cpu, 0, fmt.Errorf("sysctl hw.memsize: %w", err)
+		}
+		return cpu, memsize / (1024 * 1024), nil
+	case "linux":
+		data, err := os.ReadFile("/proc/meminfo")
+		if err != nil {
+			return cpu, 0, err
+		}
+		kb, err := memTotalKB(data)
+		return cpu, kb / 1024, err
+	}
+	return cpu, 0, fmt.Errorf("cannot detect memory on %s", runtime.GOOS)
+}
+
+// memTotalKB extracts MemTotal from the contents of /proc/meminfo.
+func memTotalKB(meminfo []byte) (int64, error) {
+	s := bufio.NewScanner(bytes.NewReader(meminfo))
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) >= 2 && fields[0] == "MemTotal:" {
+			return strconv.ParseInt(fields[1], 10, 64)
+		}
+	}
+	return 0, errors.New("no MemTotal in /proc/meminfo")
+}
+
+// HostCapacity returns the budget VMs may use: the configured or detected
+// host resources scaled by the overcommit ratio. Memory that cannot be
+// detected is left unlimited and reported in the returned warning.
+func (c *FleetConfig) HostCapacity() (capacity Capacity, warning string, err error) {
+	host := c.Host
+	memoryMB, err := ParseSize(host.Memory)
+	if err != nil {
+		return Capacity{}, "", fmt.Errorf("fleet: host.memory: %w", err)
+	}
+	if host.Overcommit < 0 {
+		return Capacity{}, "", fmt.Errorf("fleet: host.overcommit must be positive, got %v", host.Overcommit)
+	}
+
+	cpu := host.CPU
+	if cpu == 0 || memoryMB == 0 {
+		detectedCPU, detectedMemory, err := detectHost()
+		if cpu == 0 {
+			cpu = detectedCPU
+		}
+		if memoryMB == 0 {
+			memoryMB = detectedMemory
+			if err != nil {
+				warning = fmt.Sprintf("cannot detect host memory (%v); set host.memory to limit VM memory", err)
+			}
+		}
+	}
+
+	ratio := host.Overcommit
+	if ratio == 0 {
+		ratio = 1
+	}
+	return Capacity{
+		CPU:      int(float64(cpu) * ratio),
+		MemoryMB: int64(float64(memoryMB) * ratio),
+	}, warning, nil
+}
+
+// Consumer is a VM counted against the host budget.
+type Consumer struct {
+	Name     string
+	CPU      int
+	MemoryMB int64
+	// External is set for VMs that are not part of the fleet config.
+	External bool
+}
+
+func (c Consumer) String() string {
+	s := fmt.Sprintf("%s (%d CPU, %s", c.Name, c.CPU, FormatSize(c.MemoryMB))
+	if c.External {
+		s += ", not in fleet"
+	}
+	return s + ")"
+}
+
+// Budget tracks how much of the host capacity running VMs use.
+type Budget struct {
+	Capacity  Capacity
+	Consumers []Consumer
+}
+
+// NewBudget counts every VM in actual that is not stopped against capacity,
+// whether or not it belongs to the fleet. fleetNames are the VM names the
+// config defines.
+func NewBudget(capacity Capacity, actual []lume.VM, fleetNames []string) *Budget {
+	inFleet := make(map[string]bool, len(fleetNames))
+	for _, name := range fleetNames {
+		inFleet[name] = true
+	}
+	b := &Budget{Capacity: capacity}
+	for _, vm := range actual {
+		if strings.EqualFold(vm.Status, "stopped") {
+			continue
+		}
+		b.Consumers = append(b.Consumers, Consumer{
+			Name:     vm.Name,
+			CPU:      vm.CPUCount,
+			MemoryMB: vm.MemorySize / (1024 * 1024),
+			External: !inFleet[vm.Name],
+		})
+	}
+	return b
+}
+
+// Used returns the resources claimed by all consumers.
+func (b *Budget) Used() Capacity {
+	var used Capacity
+	for _, c := range b.Consumers {
+		used.CPU += c.CPU
+		used.MemoryMB += c.MemoryMB
+	}
+	return used
+}
+
+// Release returns the resources of the named VM to the budget.
+func (b *Budget) Release(name string) {
+	b.Consumers = slices.DeleteFunc(b.Consumers, func(c Consumer) bool { return c.Name == name })
+}
+
+// demand returns the resources vm needs when it runs. Existing VMs are
+// counted with their actual resources.
+func demand(vm ResolvedVM, current *lume.VM) Consumer {
+	if current != nil {
+		return Consumer{Name: vm.Name, CPU: current.CPUCount, MemoryMB: current.MemorySize / (1024 * 1024)}
+	}
+	memoryMB, _ := ParseSize(vm.Memory)
+	return Consumer{Name: vm.Name, CPU: vm.CPU, MemoryMB: memoryMB}
+}
+
+// Reserve claims the resources needed to run vm, or returns an error naming
+// the VMs that use the budget when they do not fit. current is the existing
+// VM, if any.
+func (b *Budget) Reserve(vm ResolvedVM, current *lume.VM) error {
+	need := demand(vm, current)
+	used := b.Used()
+
+	var short []string
+	if b.Capacity.CPU > 0 && used.CPU+need.CPU > b.Capacity.CPU {
+		short = append(short, fmt.Sprintf("%d CPU (%d of %d free)", need.CPU, max(b.Capacity.CPU-used.CPU, 0), b.Capacity.CPU))
+	}
+	if b.Capacity.MemoryMB > 0 && used.MemoryMB+need.MemoryMB > b.Capacity.MemoryMB {
+		short = append(short, fmt.Sprintf("%s memory (%s of %s free)",
+			FormatSize(need.MemoryMB), FormatSize(max(b.Capacity.MemoryMB-used.MemoryMB, 0)), FormatSize(b.Capacity.MemoryMB)))
+	}
+	if len(short) > 0 {
+		msg := "host capacity exceeded: needs " + strings.Join(short, " and ")
+		if len(b.Consumers) > 0 {
+			consumers := make([]string, len(b.Consumers))
+			for i, c := range b.Consumers {
+				consumers[i] = c.String()
+			}
+			msg += "; in use by " + strings.Join(consumers, ", ")
+		}
+		return errors.New(msg)
+	}
+
+	b.Consumers = append(b.Consumers, need)
+	return nil
+}
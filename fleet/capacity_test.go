@@ -0,0 +1,121 @@
+package fleet
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hoalong/lume-fleet/lume"
+)
+
+func stubDetectHost(t *testing.T, cpu int, memoryMB int64, err error) {
+	t.Helper()
+	orig := detectHost
+	detectHost = func() (int, int64, error) { return cpu, memoryMB, err }
+	t.Cleanup(func() { detectHost = orig })
+}
+
+func TestMemTotalKB(t *testing.T) {
+	kb, err := memTotalKB([]byte("MemFree:  100 kB\nMemTotal:       16384000 kB\n"))
+	if err != nil || kb != 16384000 {
+		t.Fatalf("memTotalKB() = %d, %v, want 16384000", kb, err)
+	}
+	if _, err := memTotalKB([]byte("MemFree: 1 kB\n")); err == nil {
+		t.Fatalf("memTotalKB() without MemTotal returned no error")
+	}
+}
+
+func TestHostCapacity(t *testing.T) {
+	stubDetectHost(t, 10, 32*1024, nil)
+
+	tests := []struct {
+		name string
+		host HostSpec
+		want Capacity
+	}{
+		{"detected", HostSpec{}, Capacity{CPU: 10, MemoryMB: 32 * 1024}},
+		{"configured", HostSpec{CPU: 8, Memory: "24GB"}, Capacity{CPU: 8, MemoryMB: 24 * 1024}},
+		{"overcommit", HostSpec{CPU: 8, Overcommit: 1.5}, Capacity{CPU: 12, MemoryMB: 48 * 1024}},
+	}
+	for _, tt := range tests {
+		cfg := FleetConfig{Host: tt.host}
+		got, warning, err := cfg.HostCapacity()
+		if err != nil || warning != "" {
+			t.Fatalf("%s: HostCapacity() returned %q, %v", tt.name, warning, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: HostCapacity() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHostCapacityWarnsWhenMemoryIsUnknown(t *testing.T) {
+	stubDetectHost(t, 4, 0, errors.New("no sysctl"))
+
+	cfg := FleetConfig{}
+	got, warning, err := cfg.HostCapacity()
+	if err != nil {
+		t.Fatalf("HostCapacity() returned error: %v", err)
+	}
+	if got != (Capacity{CPU: 4}) || !strings.Contains(warning, "host.memory") {
+		t.Fatalf("HostCapacity() = %+v, %q, want CPU only and a warning", got, warning)
+	}
+}
+
+func TestBudgetReserve(t *testing.T) {
+	actual := []lume.VM{
+		{Name: "dev", Status: "running", CPUCount: 4, MemorySize: 8 * gb},
+		{Name: "scratch", Status: "running", CPUCount: 2, MemorySize: 4 * gb},
+		{Name: "old", Status: "stopped", CPUCount: 8, MemorySize: 16 * gb},
+	}
+	budget := NewBudget(Capacity{CPU: 10, MemoryMB: 16 * 1024}, actual, []string{"dev", "ci", "old"})
+
+	if err := budget.Reserve(ResolvedVM{Name: "ci", CPU: 4, Memory: "4GB"}, nil); err != nil {
+		t.Fatalf("Reserve(ci) returned error: %v", err)
+	}
+	if got := budget.Used(); got != (Capacity{CPU: 10, MemoryMB: 16 * 1024}) {
+		t.Fatalf("Used() = %+v, want the whole budget", got)
+	}
+
+	// Existing VMs count with their actual resources, not the config.
+	err := budget.Reserve(ResolvedVM{Name: "old", CPU: 1, Memory: "1GB"}, &actual[2])
+	if err == nil {
+		t.Fatalf("Reserve(old) returned no error")
+	}
+	for _, want := range []string{
+		"needs 8 CPU (0 of 10 free) and 16GB memory (0MB of 16GB free)",
+		"dev (4 CPU, 8GB)",
+		"scratch (2 CPU, 4GB, not in fleet)",
+		"ci (4 CPU, 4GB)",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Reserve(old) error = %q, want it to contain %q", err, want)
+		}
+	}
+}
+
+func TestValidateReportsHostProblemsWithPositions(t *testing.T) {
+	path := writeConfig(t, `host:
+  memory: lots
+  macos-limit: -1
+vms:
+  dev: {}
+`)
+
+	problems, err := ValidateConfig(LoadOptions{Paths: []string{path}})
+	if err != nil {
+		t.Fatalf("ValidateConfig() returned error: %v", err)
+	}
+	want := []string{
+		path + `:2:11: host: invalid memory`,
+		path + `:3:16: host: invalid macos-limit -1`,
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("ValidateConfig() returned %d problems, want %d:\n%s", len(problems), len(want), problems)
+	}
+	for i, w := range want {
+		if !strings.HasPrefix(problems[i].String(), w) {
+			t.Errorf("problem %d = %q, want prefix %q", i, problems[i], w)
+		}
+	}
+}
@@ -2,7 +2,8 @@ package fleet
 
 import (
 	"fmt"
-	"os"
+	"maps"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -11,54 +12,174 @@ import (
 
 // FleetConfig is the top-level fleet.yml structure.
 type FleetConfig struct {
+	Version  int               `yaml:"version,omitempty"`
+	Include  []string          `yaml:"include,omitempty"`
 	Defaults VMDefaults        `yaml:"defaults"`
+	Profiles map[string]VMSpec `yaml:"profiles,omitempty"`
 	VMs      map[string]VMSpec `yaml:"vms"`
+
+	// Sizes adds size presets or changes fields of the built-in ones.
+	Sizes map[string]SizePreset `yaml:"sizes,omitempty"`
+	// Host sets the capacity `up` schedules VMs against.
+	Host HostSpec `yaml:"host,omitempty"`
+
+	// Warnings are non-fatal notices from loading, such as deprecated
+	// schema versions that were upgraded in memory.
+	Warnings []string `yaml:"-"`
+
+	// vmOrder lists VM names in the order they first appear in the config
+	// files.
+	vmOrder []string
+
+	// nulls lists, per block ("vms", "profiles"), the entries explicitly set
+	// to null. Overlay files use this to remove entries.
+	nulls map[string][]string
+
+	// rawDefaults records, per defaults key, whether the value that set it
+	// used ${VAR} interpolation, so fmt never compares against the expanded
+	// value.
+	rawDefaults map[string]bool
 }
 
-// VMDefaults provides default values inherited by all VMs.
-type VMDefaults struct {
-	OS         string `yaml:"os"`
-	CPU        int    `yaml:"cpu"`
-	Memory     string `yaml:"memory"`
-	DiskSize   string `yaml:"disk-size"`
-	Unattended string `yaml:"unattended"`
-	Image      string `yaml:"image"`
-	VNCPort    int    `yaml:"vnc-port"`
-	Storage    string `yaml:"storage"`
+// VMNames returns the names of the VMs in config order: the order they
+// first appear in the config files, followed by VMs added in code, sorted
+// by name.
+func (c *FleetConfig) VMNames() []string {
+	names := make([]string, 0, len(c.VMs))
+	for _, name := range c.vmOrder {
+		if _, ok := c.VMs[name]; ok && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.VMs)) {
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
 }
 
+// VMDefaults provides default values inherited by all VMs. Any field that
+// can be set on a VM can also be set here.
+type VMDefaults VMSpec
+
 // VMSpec is one VM entry in the fleet.
 type VMSpec struct {
-	OS         string   `yaml:"os,omitempty"`
-	CPU        int      `yaml:"cpu,omitempty"`
-	Memory     string   `yaml:"memory,omitempty"`
-	DiskSize   string   `yaml:"disk-size,omitempty"`
-	SharedDir  string   `yaml:"shared-dir,omitempty"`
-	Unattended string   `yaml:"unattended,omitempty"`
-	Image      string   `yaml:"image,omitempty"`
-	VNCPort    int      `yaml:"vnc-port,omitempty"`
-	Storage    string   `yaml:"storage,omitempty"`
-	Tags       []string `yaml:"tags,omitempty"`
-	Autostart  *bool    `yaml:"autostart,omitempty"`
+	OS         string            `yaml:"os,omitempty"`
+	Size       string            `yaml:"size,omitempty"`
+	CPU        int               `yaml:"cpu,omitempty"`
+	Memory     string            `yaml:"memory,omitempty"`
+	DiskSize   string            `yaml:"disk-size,omitempty"`
+	SharedDirs []SharedDir       `yaml:"shared-dirs,omitempty"`
+	Unattended string            `yaml:"unattended,omitempty"`
+	Image      string            `yaml:"image,omitempty"`
+	VNCPort    int               `yaml:"vnc-port,omitempty"`
+	Storage    string            `yaml:"storage,omitempty"`
+	Tags       []string          `yaml:"tags,omitempty"`
+	Labels     map[string]string `yaml:"labels,omitempty"`
+	Autostart  *bool             `yaml:"autostart,omitempty"`
+	SSH        *SSHSpec          `yaml:"ssh,omitempty"`
+	Priority   int               `yaml:"priority,omitempty"`
+	Order      int               `yaml:"order,omitempty"`
+
+	// Extends lists profiles applied, in order, before this spec.
+	Extends []string `yaml:"extends,omitempty"`
+	// Override allows this definition to replace fields of a VM or profile
+	// with the same name from an earlier config file.
+	Override bool `yaml:"override,omitempty"`
+
+	// ReplaceTags makes Tags replace inherited tags instead of adding to them.
+	ReplaceTags bool `yaml:"replace-tags,omitempty"`
+
+	// present records the keys set in the YAML source, and where, so that
+	// explicit zero values (vnc-port: 0, image: "") still override inherited
+	// values. It is nil for specs built in code, where non-zero values count
+	// as set.
+	present map[string]Position
+	// at is where the spec itself starts in the YAML source.
+	at Position
 }
 
-// LoadConfig reads and parses a fleet.yml file.
-func LoadConfig(path string) (*FleetConfig, error) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("fleet: read config %q: %w", path, err)
+// UnmarshalYAML decodes a VM spec and records which keys were present.
+func (s *VMSpec) UnmarshalYAML(node *yaml.Node) error {
+	type plain VMSpec
+	if err := node.Decode((*plain)(s)); err != nil {
+		return err
+	}
+	s.present = presentKeys(node)
+	s.at = positionOf(node)
+	return nil
+}
+
+// UnmarshalYAML decodes the defaults block like a VM spec.
+func (d *VMDefaults) UnmarshalYAML(node *yaml.Node) error {
+	return (*VMSpec)(d).UnmarshalYAML(node)
+}
+
+// isSet reports whether key was set on the spec. Specs that were not decoded
+// from YAML fall back to nonZero.
+func (s VMSpec) isSet(key string, nonZero bool) bool {
+	if s.present == nil {
+		return nonZero
 	}
+	_, ok := s.present[key]
+	return ok
+}
+
+// position returns where key was set, falling back to the spec itself.
+func (s VMSpec) position(key string) Position {
+	if pos, ok := s.present[key]; ok {
+		return pos
+	}
+	return s.at
+}
 
-	var cfg FleetConfig
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
-		return nil, fmt.Errorf("fleet: parse config %q: %w", path, err)
+// setFile records the config file the spec was decoded from.
+func (s *VMSpec) setFile(path string) {
+	s.at.File = path
+	for key, pos := range s.present {
+		pos.File = path
+		s.present[key] = pos
 	}
+}
 
-	if len(cfg.VMs) == 0 {
-		return nil, fmt.Errorf("fleet: no VMs defined in %q", path)
+// presentKeys returns the keys of a mapping node and the position of each
+// value, following `<<` merge keys.
+func presentKeys(node *yaml.Node) map[string]Position {
+	keys := map[string]Position{}
+	var walk func(n *yaml.Node)
+	walk = func(n *yaml.Node) {
+		if n.Kind == yaml.AliasNode {
+			n = n.Alias
+		}
+		switch n.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				if n.Content[i].Value == "<<" {
+					walk(n.Content[i+1])
+					continue
+				}
+				if _, ok := keys[n.Content[i].Value]; !ok {
+					keys[n.Content[i].Value] = positionOf(n.Content[i+1])
+				}
+			}
+		case yaml.SequenceNode:
+			for _, c := range n.Content {
+				walk(c)
+			}
+		}
 	}
+	walk(node)
+	return keys
+}
 
-	return &cfg, nil
+// SharedDir is one host directory shared into a VM when it runs.
+type SharedDir struct {
+	Path     string `yaml:"path" json:"path"`
+	ReadOnly bool   `yaml:"read-only,omitempty" json:"readOnly"`
+	// Tag is the mount tag inside the guest. lume run cannot set it yet, so
+	// it is only reported and a warning is printed when it is set.
+	Tag string `yaml:"tag,omitempty" json:"tag,omitempty"`
 }
 
 // ParseSize converts a human-readable size like "8GB" or "512MB" to megabytes.
@@ -94,3 +215,16 @@ func ParseSize(s string) (int64, error) {
 
 	return int64(n * float64(multiplier)), nil
 }
+
+// FormatSize renders megabytes as a size string accepted by ParseSize, using
+// the largest unit that represents the value exactly.
+func FormatSize(mb int64) string {
+	switch {
+	case mb != 0 && mb%(1024*1024) == 0:
+		return strconv.FormatInt(mb/(1024*1024), 10) + "TB"
+	case mb != 0 && mb%1024 == 0:
+		return strconv.FormatInt(mb/1024, 10) + "GB"
+	default:
+		return strconv.FormatInt(mb, 10) + "MB"
+	}
+}
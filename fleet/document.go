@@ -0,0 +1,363 @@
+package fleet
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Document is a fleet.yml file held as a yaml.v3 node tree, so that edits
+// keep comments, key order and anchors.
+type Document struct {
+	path string
+	raw  []byte // file contents as read, nil for new documents
+	doc  yaml.Node
+}
+
+// OpenDocument reads path into a Document.
+func OpenDocument(path string) (*Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("fleet: read config %q: %w", path, err)
+	}
+	d := &Document{path: path, raw: data}
+	if err := yaml.Unmarshal(data, &d.doc); err != nil {
+		return nil, fmt.Errorf("fleet: parse config %q: %w", path, err)
+	}
+	if len(d.doc.Content) == 0 {
+		d.doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	if d.root().Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("fleet: config %q is not a mapping", path)
+	}
+	return d, nil
+}
+
+// NewDocument creates a document for path holding cfg. Nothing is written
+// until Save is called.
+func NewDocument(path string, cfg FleetConfig) (*Document, error) {
+	var root yaml.Node
+	if err := root.Encode(&cfg); err != nil {
+		return nil, fmt.Errorf("fleet: encode config: %w", err)
+	}
+	return &Document{
+		path: path,
+		doc:  yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{&root}},
+	}, nil
+}
+
+// Path returns the file the document was read from.
+func (d *Document) Path() string { return d.path }
+
+func (d *Document) root() *yaml.Node { return d.doc.Content[0] }
+
+// HasVM reports whether the document defines a VM called name.
+func (d *Document) HasVM(name string) bool {
+	return mappingValue(mappingValue(d.root(), "vms"), name) != nil
+}
+
+// Defaults decodes the document's defaults block.
+func (d *Document) Defaults() (VMDefaults, error) {
+	var defaults VMDefaults
+	if node := mappingValue(d.root(), "defaults"); node != nil {
+		if err := node.Decode(&defaults); err != nil {
+			return VMDefaults{}, fmt.Errorf("fleet: decode defaults in %q: %w", d.path, err)
+		}
+	}
+	return defaults, nil
+}
+
+// AddVM appends a VM definition to the vms block, creating the block if
+// needed. It fails if the VM already exists.
+func (d *Document) AddVM(name string, spec VMSpec) error {
+	if d.HasVM(name) {
+		return fmt.Errorf("VM %q already exists in %s", name, d.path)
+	}
+	var value yaml.Node
+	if err := value.Encode(spec); err != nil {
+		return fmt.Errorf("encode VM %q: %w", name, err)
+	}
+	if value.Kind == yaml.MappingNode {
+		value.Style = 0
+	}
+	vms := ensureMapping(d.root(), "vms")
+	vms.Content = append(vms.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name}, &value)
+	return nil
+}
+
+// RemoveVM deletes a VM definition, including the comments attached to it.
+func (d *Document) RemoveVM(name string) error {
+	vms := mappingValue(d.root(), "vms")
+	if vms == nil || vms.Kind != yaml.MappingNode {
+		return fmt.Errorf("VM %q not found in %s", name, d.path)
+	}
+	for i := 0; i+1 < len(vms.Content); i += 2 {
+		if vms.Content[i].Value == name {
+			vms.Content = append(vms.Content[:i], vms.Content[i+2:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("VM %q not found in %s", name, d.path)
+}
+
+// Get returns the node at a dotted path such as "vms.dev-main.cpu" or
+// "vms.dev-main.tags.0". Aliases are followed.
+func (d *Document) Get(path string) (*yaml.Node, error) {
+	node := d.root()
+	for _, key := range splitPath(path) {
+		child, err := childNode(node, key)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if child == nil {
+			return nil, fmt.Errorf("%s: %q not found", path, key)
+		}
+		node = child
+	}
+	return node, nil
+}
+
+// Set stores value, encoded as YAML, at a dotted path. Missing mappings
+// along the path are created. An existing value keeps its comments; if it
+// was an alias, only this use of the anchor is replaced.
+func (d *Document) Set(path string, value any) error {
+	var node yaml.Node
+	if err := node.Encode(value); err != nil {
+		return fmt.Errorf("%s: encode value: %w", path, err)
+	}
+	return d.setNode(path, &node)
+}
+
+// SetText parses text as a YAML value ("8", "16GB", "[ci, dev]", "true")
+// and stores it at a dotted path like Set.
+func (d *Document) SetText(path, text string) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(text), &doc); err != nil {
+		return fmt.Errorf("%s: parse value %q: %w", path, text, err)
+	}
+	if len(doc.Content) == 0 {
+		return d.setNode(path, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"})
+	}
+	return d.setNode(path, doc.Content[0])
+}
+
+func (d *Document) setNode(path string, value *yaml.Node) error {
+	keys := splitPath(path)
+	node := d.root()
+	for i, key := range keys {
+		last := i == len(keys)-1
+		if node.Kind == yaml.AliasNode {
+			node = node.Alias
+		}
+
+		if node.Kind == yaml.SequenceNode {
+			idx, err := strconv.Atoi(key)
+			if err != nil || idx < 0 || idx > len(node.Content) {
+				return fmt.Errorf("%s: invalid index %q", path, key)
+			}
+			if idx == len(node.Content) {
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"})
+			}
+			if last {
+				replaceNode(node.Content[idx], value)
+				return nil
+			}
+			node = node.Content[idx]
+			continue
+		}
+
+		if node.Kind != yaml.MappingNode {
+			return fmt.Errorf("%s: %q is not a mapping", path, strings.Join(keys[:i], "."))
+		}
+		child := mappingValue(node, key)
+		if child == nil {
+			child = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			if last {
+				child = value
+			}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, child)
+			if node.Style == yaml.FlowStyle && child.Kind == yaml.MappingNode {
+				child.Style = yaml.FlowStyle
+			}
+			if last {
+				return nil
+			}
+		} else if last {
+			replaceNode(child, value)
+			return nil
+		}
+		node = child
+	}
+	return fmt.Errorf("%s: empty path", path)
+}
+
+// replaceNode overwrites dst with src, keeping dst's comments and anchor.
+func replaceNode(dst, src *yaml.Node) {
+	head, line, foot, anchor := dst.HeadComment, dst.LineComment, dst.FootComment, dst.Anchor
+	if dst.Kind == yaml.AliasNode {
+		anchor = ""
+	}
+	*dst = *src
+	dst.HeadComment, dst.LineComment, dst.FootComment, dst.Anchor = head, line, foot, anchor
+}
+
+// childNode returns the child of node for key, or nil if there is none.
+func childNode(node *yaml.Node, key string) (*yaml.Node, error) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	var child *yaml.Node
+	switch node.Kind {
+	case yaml.MappingNode:
+		child = mappingValue(node, key)
+	case yaml.SequenceNode:
+		idx, err := strconv.Atoi(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid index %q", key)
+		}
+		if idx >= 0 && idx < len(node.Content) {
+			child = node.Content[idx]
+		}
+	default:
+		return nil, fmt.Errorf("cannot look up %q in a scalar", key)
+	}
+	if child != nil && child.Kind == yaml.AliasNode {
+		child = child.Alias
+	}
+	return child, nil
+}
+
+func splitPath(path string) []string {
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, ".")
+}
+
+// Check validates the edited document in the context of the full config
+// selected by opts, as if it had been saved: the merged config must load
+// cleanly and every VM must resolve.
+func (d *Document) Check(opts LoadOptions) error {
+	data, err := d.Bytes()
+	if err != nil {
+		return err
+	}
+	abs, err := filepath.Abs(d.path)
+	if err != nil {
+		return fmt.Errorf("fleet: resolve path %q: %w", d.path, err)
+	}
+	opts.contents = map[string][]byte{abs: data}
+	if !slices.ContainsFunc(opts.Paths, func(p string) bool {
+		a, _ := filepath.Abs(p)
+		return a == abs
+	}) {
+		opts.Paths = append(slices.Clone(opts.Paths), d.path)
+	}
+
+	cfg, problems, err := loadConfig(opts)
+	if err != nil {
+		return err
+	}
+	if len(problems) == 0 {
+		_, err = cfg.Resolve()
+		if err != nil {
+			problems = asProblems(err)
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("fleet: %s would be invalid:\n%w", d.path, problems)
+	}
+	return nil
+}
+
+// Bytes renders the document with two-space indentation.
+func (d *Document) Bytes() ([]byte, error) {
+	return encodeYAML(&d.doc)
+}
+
+// Changed reports whether the rendered document differs from the file
+// contents it was read from.
+func (d *Document) Changed() (bool, error) {
+	data, err := d.Bytes()
+	if err != nil {
+		return false, err
+	}
+	return !bytes.Equal(data, d.raw), nil
+}
+
+// Save writes the document back to its file atomically.
+func (d *Document) Save() error {
+	data, err := d.Bytes()
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(d.path, data)
+}
+
+// ensureMapping returns the mapping under key in node, adding an empty one
+// at the end if it is missing or null.
+func ensureMapping(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			v := node.Content[i+1]
+			if v.Kind != yaml.MappingNode {
+				*v = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", HeadComment: v.HeadComment, LineComment: v.LineComment}
+			}
+			return v
+		}
+	}
+	v := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, v)
+	return v
+}
+
+// encodeYAML marshals v with the two-space indentation used by fleet.yml.
+func encodeYAML(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeFileAtomic replaces path with data via a temporary file in the same
+// directory, keeping the existing file mode.
+func writeFileAtomic(path string, data []byte) error {
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("fleet: write %q: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("fleet: write %q: %w", path, err)
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return fmt.Errorf("fleet: write %q: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("fleet: write %q: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("fleet: write %q: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package fleet
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDocumentAddVMPreservesComments(t *testing.T) {
+	path := writeConfig(t, `# team fleet
+defaults:
+  cpu: 4 # plenty
+vms:
+  # main dev box
+  dev-main:
+    memory: 16GB
+`)
+
+	doc, err := OpenDocument(path)
+	if err != nil {
+		t.Fatalf("OpenDocument() returned error: %v", err)
+	}
+	if err := doc.AddVM("linux-1", VMSpec{OS: "linux", CPU: 2}); err != nil {
+		t.Fatalf("AddVM() returned error: %v", err)
+	}
+	if err := doc.AddVM("dev-main", VMSpec{}); err == nil {
+		t.Fatalf("AddVM() of an existing VM returned no error")
+	}
+	if err := doc.Save(); err != nil {
+		t.Fatalf("Save() returned error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+	for _, want := range []string{"# team fleet", "cpu: 4 # plenty", "# main dev box", "  linux-1:\n    os: linux\n    cpu: 2\n"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("saved config missing %q:\n%s", want, data)
+		}
+	}
+}
+
+func TestDocumentSetAndGetKeepCommentsAndAnchors(t *testing.T) {
+	path := writeConfig(t, `defaults: &defs
+  cpu: 4
+vms:
+  dev-main:
+    memory: 16GB # needs room
+  ci-runner-1:
+    <<: *defs
+`)
+
+	doc, err := OpenDocument(path)
+	if err != nil {
+		t.Fatalf("OpenDocument() returned error: %v", err)
+	}
+	for _, edit := range [][2]string{
+		{"vms.dev-main.memory", "32GB"},
+		{"vms.ci-runner-1.cpu", "2"},
+		{"vms.dev-main.tags", "[dev, ios]"},
+		{"defaults.disk-size", "80GB"},
+		{"vms.dev-main.tags.1", "android"},
+		{"vms.scratch.os", "linux"},
+		{"vms.scratch.vnc-port", "0"},
+	} {
+		if err := doc.SetText(edit[0], edit[1]); err != nil {
+			t.Fatalf("SetText(%q) returned error: %v", edit[0], err)
+		}
+	}
+
+	node, err := doc.Get("vms.dev-main.tags.1")
+	if err != nil || node.Value != "android" {
+		t.Fatalf("Get(tags.1) = %v, %v, want android", node, err)
+	}
+	if _, err := doc.Get("vms.missing.cpu"); err == nil {
+		t.Fatalf("Get() of a missing path returned no error")
+	}
+
+	data, err := doc.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes() returned error: %v", err)
+	}
+	for _, want := range []string{"defaults: &defs", "memory: 32GB # needs room", "<<: *defs\n    cpu: 2", "tags: [dev, android]", "scratch:\n    os: linux\n    vnc-port: 0"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("document missing %q:\n%s", want, data)
+		}
+	}
+}
+
+func TestDocumentCheckRejectsInvalidEdits(t *testing.T) {
+	path := writeConfig(t, `vms:
+  dev-main:
+    memory: 16GB
+`)
+
+	doc, err := OpenDocument(path)
+	if err != nil {
+		t.Fatalf("OpenDocument() returned error: %v", err)
+	}
+	opts := LoadOptions{Paths: []string{path}}
+
+	if err := doc.SetText("vms.dev-main.memory", "lots"); err != nil {
+		t.Fatalf("SetText() returned error: %v", err)
+	}
+	if err := doc.Check(opts); err == nil || !strings.Contains(err.Error(), "invalid memory") {
+		t.Fatalf("Check() error = %v, want invalid memory", err)
+	}
+
+	if err := doc.SetText("vms.dev-main.memory", "24GB"); err != nil {
+		t.Fatalf("SetText() returned error: %v", err)
+	}
+	if err := doc.Check(opts); err != nil {
+		t.Fatalf("Check() returned error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+	if !strings.Contains(string(data), "16GB") {
+		t.Fatalf("Check() must not write the file:\n%s", data)
+	}
+}
@@ -0,0 +1,203 @@
+package fleet
+
+import (
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// sizeKeys are the keys whose values are size strings.
+var sizeKeys = []string{"memory", "disk-size"}
+
+// NormalizeSize rewrites a size string in canonical form, e.g. "8 gb" to
+// "8GB" or "1.50GB" to "1.5GB". The unit is kept as written.
+func NormalizeSize(s string) (string, error) {
+	if _, err := ParseSize(s); err != nil {
+		return "", err
+	}
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if s == "" {
+		return "", nil
+	}
+	unit := s[len(s)-2:]
+	n, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(s, unit)), 64)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatFloat(n, 'f', -1, 64) + unit, nil
+}
+
+// Format rewrites the document in canonical form: size strings are
+// normalized, VMs are optionally sorted by name, and VM values that merely
+// repeat the defaults are removed. Only the defaults and profiles of the
+// document itself and the files it includes count: drop-ins and overlays
+// may differ between checkouts, so relying on them would change the config
+// for everyone without them. Values are kept when that context does not
+// load cleanly. Comments are kept.
+func (d *Document) Format(sortVMs bool) {
+	var cfg *FleetConfig
+	if data, err := d.Bytes(); err == nil {
+		if c, problems, err := loadFile(d.path, data); err == nil && len(problems) == 0 {
+			cfg = c
+		}
+	}
+	root := d.root()
+
+	normalize := func(spec *yaml.Node) {
+		for _, key := range sizeKeys {
+			if v := mappingValue(spec, key); v != nil && v.Kind == yaml.ScalarNode && !strings.Contains(v.Value, "$") {
+				if norm, err := NormalizeSize(v.Value); err == nil {
+					v.Value = norm
+				}
+			}
+		}
+	}
+	forEachSpec(root, func(_ string, spec *yaml.Node) { normalize(spec) })
+	if sizes := mappingValue(root, "sizes"); sizes != nil && sizes.Kind == yaml.MappingNode {
+		for i := 1; i < len(sizes.Content); i += 2 {
+			normalize(sizes.Content[i])
+		}
+	}
+
+	vms := mappingValue(root, "vms")
+	if vms == nil || vms.Kind != yaml.MappingNode {
+		return
+	}
+
+	if cfg != nil {
+		for i := 0; i+1 < len(vms.Content); i += 2 {
+			removeRedundant(vms.Content[i+1], cfg)
+		}
+	}
+
+	if sortVMs {
+		type pair struct{ key, value *yaml.Node }
+		pairs := make([]pair, 0, len(vms.Content)/2)
+		for i := 0; i+1 < len(vms.Content); i += 2 {
+			pairs = append(pairs, pair{vms.Content[i], vms.Content[i+1]})
+		}
+		slices.SortStableFunc(pairs, func(a, b pair) int {
+			return strings.Compare(a.key.Value, b.key.Value)
+		})
+		vms.Content = vms.Content[:0]
+		for _, p := range pairs {
+			vms.Content = append(vms.Content, p.key, p.value)
+		}
+	}
+}
+
+// forEachSpec calls fn for the defaults block and every profile and VM
+// mapping in root.
+func forEachSpec(root *yaml.Node, fn func(block string, spec *yaml.Node)) {
+	if defaults := mappingValue(root, "defaults"); defaults != nil && defaults.Kind == yaml.MappingNode {
+		fn("defaults", defaults)
+	}
+	for _, block := range []string{"profiles", "vms"} {
+		specs := mappingValue(root, block)
+		if specs == nil || specs.Kind != yaml.MappingNode {
+			continue
+		}
+		for i := 1; i < len(specs.Content); i += 2 {
+			if specs.Content[i].Kind == yaml.MappingNode {
+				fn(block, specs.Content[i])
+			}
+		}
+	}
+}
+
+// removeRedundant drops scalar values from a VM mapping that equal the
+// merged defaults, unless a profile the VM extends also sets them (removing
+// the value would then change the result) or either side uses ${VAR}
+// interpolation (the result would depend on the environment fmt ran in).
+// VMs that override an earlier definition are left alone.
+func removeRedundant(vm *yaml.Node, cfg *FleetConfig) {
+	if vm.Kind != yaml.MappingNode {
+		return
+	}
+	var spec VMSpec
+	if err := vm.Decode(&spec); err != nil || spec.Override {
+		return
+	}
+	fromProfiles, ok := profileKeys(cfg, spec.Extends, nil)
+	if !ok {
+		return
+	}
+	if spec.Size != "" || fromProfiles["size"] {
+		// The preset sits between the defaults and these values.
+		for _, key := range presetKeys {
+			fromProfiles[key] = true
+		}
+	}
+
+	defaults := VMSpec(cfg.Defaults)
+	dv := reflect.ValueOf(defaults)
+	fields := yamlFields(reflect.TypeOf(VMSpec{}))
+
+	kept := vm.Content[:0]
+	for i := 0; i+1 < len(vm.Content); i += 2 {
+		key, value := vm.Content[i], vm.Content[i+1]
+		field, known := fields[key.Value]
+		redundant := known && value.Kind == yaml.ScalarNode && !fromProfiles[key.Value] &&
+			!strings.Contains(value.Value, "$") && !cfg.rawDefaults[key.Value] &&
+			defaults.isSet(key.Value, !dv.FieldByIndex(field.Index).IsZero()) &&
+			sameScalar(key.Value, value.Value, dv.FieldByIndex(field.Index))
+		if !redundant {
+			kept = append(kept, key, value)
+		}
+	}
+	vm.Content = kept
+}
+
+// profileKeys returns the keys set by the named profiles and the profiles
+// they extend. ok is false if a profile is unknown or part of a cycle.
+func profileKeys(cfg *FleetConfig, names, chain []string) (keys map[string]bool, ok bool) {
+	keys = map[string]bool{}
+	for _, name := range names {
+		profile, found := cfg.Profiles[name]
+		if !found || slices.Contains(chain, name) {
+			return nil, false
+		}
+		parents, ok := profileKeys(cfg, profile.Extends, append(chain, name))
+		if !ok {
+			return nil, false
+		}
+		for k := range parents {
+			keys[k] = true
+		}
+		pv := reflect.ValueOf(profile)
+		for key, field := range yamlFields(reflect.TypeOf(VMSpec{})) {
+			if profile.isSet(key, !pv.FieldByIndex(field.Index).IsZero()) {
+				keys[key] = true
+			}
+		}
+	}
+	return keys, true
+}
+
+// sameScalar reports whether a YAML scalar equals a decoded defaults field.
+func sameScalar(key, raw string, field reflect.Value) bool {
+	if field.Kind() == reflect.Pointer {
+		if field.IsNil() {
+			return false
+		}
+		field = field.Elem()
+	}
+	var want string
+	switch field.Kind() {
+	case reflect.String:
+		want = field.String()
+	case reflect.Int:
+		want = strconv.FormatInt(field.Int(), 10)
+	case reflect.Bool:
+		want = strconv.FormatBool(field.Bool())
+	default:
+		return false
+	}
+	if slices.Contains(sizeKeys, key) {
+		return sameSize(raw, want)
+	}
+	return raw == want
+}
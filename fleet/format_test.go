@@ -0,0 +1,139 @@
+package fleet
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNormalizeSize(t *testing.T) {
+	tests := map[string]string{
+		"8gb":     "8GB",
+		" 512 mb": "512MB",
+		"1.50GB":  "1.5GB",
+		"2Tb":     "2TB",
+	}
+	for in, want := range tests {
+		got, err := NormalizeSize(in)
+		if err != nil {
+			t.Fatalf("NormalizeSize(%q) returned error: %v", in, err)
+		}
+		if got != want {
+			t.Errorf("NormalizeSize(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if _, err := NormalizeSize("8XB"); err == nil {
+		t.Errorf("NormalizeSize(8XB) returned no error")
+	}
+}
+
+func TestDocumentFormat(t *testing.T) {
+	path := writeConfig(t, `# team fleet
+defaults:
+    cpu: 4
+    memory: 8gb
+    vnc-port: 0
+profiles:
+    big:
+        cpu: 8
+vms:
+    # main dev box
+    zeta:
+        memory: 8 GB # same as defaults
+        vnc-port: 0
+        cpu: 4
+    alpha:
+        extends: [big]
+        cpu: 4
+    beta:
+        override: true
+        cpu: 4
+`)
+
+	doc, err := OpenDocument(path)
+	if err != nil {
+		t.Fatalf("OpenDocument() returned error: %v", err)
+	}
+	doc.Format(true)
+
+	data, err := doc.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes() returned error: %v", err)
+	}
+	want := `# team fleet
+defaults:
+  cpu: 4
+  memory: 8GB
+  vnc-port: 0
+profiles:
+  big:
+    cpu: 8
+vms:
+  alpha:
+    extends: [big]
+    cpu: 4
+  beta:
+    override: true
+    cpu: 4
+  # main dev box
+  zeta: {}
+`
+	if string(data) != want {
+		t.Fatalf("Format() =\n%s\nwant\n%s", data, want)
+	}
+}
+
+func TestDocumentFormatIgnoresOverlayDefaults(t *testing.T) {
+	path := writeConfig(t, `version: 2
+vms:
+  dev:
+    cpu: 4
+`)
+	overlay := filepath.Join(filepath.Dir(path), "fleet.override.yml")
+	if err := os.WriteFile(overlay, []byte("defaults:\n  cpu: 4\n"), 0o644); err != nil {
+		t.Fatalf("write overlay: %v", err)
+	}
+
+	doc, err := OpenDocument(path)
+	if err != nil {
+		t.Fatalf("OpenDocument() returned error: %v", err)
+	}
+	doc.Format(false)
+	if changed, err := doc.Changed(); err != nil || changed {
+		t.Fatalf("Changed() = %v, %v; want the VM's cpu kept", changed, err)
+	}
+}
+
+func TestDocumentFormatKeepsValuesOfInterpolatedDefaults(t *testing.T) {
+	t.Setenv("CPUS", "4")
+	path := writeConfig(t, `version: 2
+defaults:
+  cpu: ${CPUS:-4}
+  memory: 8GB
+vms:
+  dev:
+    cpu: 4
+    memory: 8GB
+`)
+
+	doc, err := OpenDocument(path)
+	if err != nil {
+		t.Fatalf("OpenDocument() returned error: %v", err)
+	}
+	doc.Format(false)
+	data, err := doc.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `version: 2
+defaults:
+  cpu: ${CPUS:-4}
+  memory: 8GB
+vms:
+  dev:
+    cpu: 4
+`
+	if string(data) != want {
+		t.Fatalf("Format() =\n%s\nwant\n%s", data, want)
+	}
+}
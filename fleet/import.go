@@ -0,0 +1,102 @@
+package fleet
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/hoalong/lume-fleet/lume"
+)
+
+// SpecFromVM converts a VM reported by `lume ls` into a VMSpec, rendering
+// byte counts as size strings.
+func SpecFromVM(vm lume.VM) VMSpec {
+	spec := VMSpec{
+		OS:     strings.ToLower(vm.OS),
+		CPU:    vm.CPUCount,
+		Memory: FormatSize(vm.MemorySize / (1024 * 1024)),
+	}
+	if vm.DiskSize != nil {
+		spec.DiskSize = FormatSize(vm.DiskSize.Total / (1024 * 1024))
+	}
+	for _, dir := range vm.SharedDirectories {
+		spec.SharedDirs = append(spec.SharedDirs, SharedDir{Path: dir})
+	}
+	return spec
+}
+
+// ImportVMs builds a config from existing VMs. When defaults is nil, values
+// shared by most VMs are factored into a new defaults block. Otherwise the
+// given defaults are kept and values equal to them are left off the VMs.
+func ImportVMs(vms []lume.VM, defaults *VMDefaults) FleetConfig {
+	cfg := FleetConfig{Version: CurrentVersion, VMs: map[string]VMSpec{}}
+	for _, vm := range vms {
+		cfg.VMs[vm.Name] = SpecFromVM(vm)
+	}
+
+	if defaults != nil {
+		cfg.Defaults = *defaults
+	} else {
+		cfg.Defaults = commonDefaults(slices.Collect(maps.Values(cfg.VMs)))
+	}
+
+	effective := ResolvedVM{}
+	effective.apply(builtinDefaults)
+	effective.apply(VMSpec(cfg.Defaults))
+	for name, spec := range cfg.VMs {
+		if spec.OS == effective.OS {
+			spec.OS = ""
+		}
+		if spec.CPU == effective.CPU {
+			spec.CPU = 0
+		}
+		if sameSize(spec.Memory, effective.Memory) {
+			spec.Memory = ""
+		}
+		if sameSize(spec.DiskSize, effective.DiskSize) {
+			spec.DiskSize = ""
+		}
+		cfg.VMs[name] = spec
+	}
+	return cfg
+}
+
+// commonDefaults returns defaults holding each value shared by more than
+// half of specs (and by at least two).
+func commonDefaults(specs []VMSpec) VMDefaults {
+	var d VMDefaults
+	d.OS = mostCommon(specs, func(s VMSpec) string { return s.OS })
+	d.Memory = mostCommon(specs, func(s VMSpec) string { return s.Memory })
+	d.DiskSize = mostCommon(specs, func(s VMSpec) string { return s.DiskSize })
+	d.CPU = mostCommon(specs, func(s VMSpec) int { return s.CPU })
+	return d
+}
+
+func mostCommon[T comparable](specs []VMSpec, field func(VMSpec) T) T {
+	var zero, best T
+	counts := map[T]int{}
+	for _, s := range specs {
+		if v := field(s); v != zero {
+			counts[v]++
+		}
+	}
+	bestCount := 0
+	for v, n := range counts {
+		if n > bestCount {
+			best, bestCount = v, n
+		}
+	}
+	if bestCount < 2 || bestCount*2 <= len(specs) {
+		return zero
+	}
+	return best
+}
+
+func sameSize(a, b string) bool {
+	if a == "" || b == "" {
+		return a == b
+	}
+	x, errA := ParseSize(a)
+	y, errB := ParseSize(b)
+	return errA == nil && errB == nil && x == y
+}
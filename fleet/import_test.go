@@ -0,0 +1,57 @@
+package fleet
+
+import (
+	"testing"
+
+	"github.com/hoalong/lume-fleet/lume"
+)
+
+const gb = 1024 * 1024 * 1024
+
+func TestFormatSize(t *testing.T) {
+	tests := map[int64]string{
+		512:         "512MB",
+		8192:        "8GB",
+		1536:        "1536MB",
+		1024 * 1024: "1TB",
+	}
+	for mb, want := range tests {
+		if got := FormatSize(mb); got != want {
+			t.Errorf("FormatSize(%d) = %q, want %q", mb, got, want)
+		}
+	}
+}
+
+func TestImportVMsFactorsCommonValuesIntoDefaults(t *testing.T) {
+	vms := []lume.VM{
+		{Name: "mac-1", OS: "macOS", CPUCount: 4, MemorySize: 8 * gb, DiskSize: &lume.DiskSize{Total: 50 * gb}},
+		{Name: "mac-2", OS: "macOS", CPUCount: 8, MemorySize: 8 * gb, DiskSize: &lume.DiskSize{Total: 50 * gb}},
+		{Name: "linux-1", OS: "linux", CPUCount: 4, MemorySize: 4 * gb, DiskSize: &lume.DiskSize{Total: 50 * gb}, SharedDirectories: []string{"/tmp"}},
+	}
+
+	cfg := ImportVMs(vms, nil)
+
+	if cfg.Defaults.OS != "macos" || cfg.Defaults.CPU != 4 || cfg.Defaults.Memory != "8GB" || cfg.Defaults.DiskSize != "50GB" {
+		t.Fatalf("defaults = %+v, want macos/4/8GB/50GB", cfg.Defaults)
+	}
+	if spec := cfg.VMs["mac-1"]; spec.OS != "" || spec.CPU != 0 || spec.Memory != "" || spec.DiskSize != "" {
+		t.Errorf("mac-1 = %+v, want everything inherited", spec)
+	}
+	if spec := cfg.VMs["mac-2"]; spec.CPU != 8 {
+		t.Errorf("mac-2 cpu = %d, want 8", spec.CPU)
+	}
+	linux := cfg.VMs["linux-1"]
+	if linux.OS != "linux" || linux.Memory != "4GB" || len(linux.SharedDirs) != 1 || linux.SharedDirs[0].Path != "/tmp" {
+		t.Errorf("linux-1 = %+v, want os linux, memory 4GB and /tmp shared", linux)
+	}
+
+	resolved, err := cfg.Resolve()
+	if err != nil {
+		t.Fatalf("Resolve() returned error: %v", err)
+	}
+	for _, vm := range resolved {
+		if vm.Name == "mac-2" && (vm.CPU != 8 || vm.Memory != "8GB" || vm.OS != "macos") {
+			t.Errorf("resolved mac-2 = %+v, want the imported values back", vm)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package fleet
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// lookupFunc looks up a variable for interpolation.
+type lookupFunc func(name string) (string, bool)
+
+// envLookup returns a lookup that prefers the process environment and falls
+// back to the .env file next to the config at path, if there is one.
+func envLookup(path string) (lookupFunc, error) {
+	dotenv, err := loadDotEnv(filepath.Join(filepath.Dir(path), ".env"))
+	if err != nil {
+		return nil, err
+	}
+	return func(name string) (string, bool) {
+		if v, ok := os.LookupEnv(name); ok {
+			return v, true
+		}
+		v, ok := dotenv[name]
+		return v, ok
+	}, nil
+}
+
+// loadDotEnv parses KEY=VALUE lines from path. Blank lines, # comments and an
+// optional "export " prefix are allowed; values may be quoted. A missing file
+// yields no variables.
+func loadDotEnv(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fleet: read %q: %w", path, err)
+	}
+	defer f.Close()
+
+	vars := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("fleet: %s:%d: expected KEY=VALUE", path, lineNo)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		vars[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("fleet: read %q: %w", path, err)
+	}
+	return vars, nil
+}
+
+// hasInterpolation reports whether any scalar under node contains a "$",
+// which interpolateNode would expand.
+func hasInterpolation(node *yaml.Node) bool {
+	switch node.Kind {
+	case yaml.AliasNode:
+		return hasInterpolation(node.Alias)
+	case yaml.ScalarNode:
+		return strings.Contains(node.Value, "$")
+	}
+	return slices.ContainsFunc(node.Content, hasInterpolation)
+}
+
+// interpolateNode expands variables in every scalar value under node. Keys
+// are left alone. Plain scalars have their tag cleared so that "${CPU}"
+// decodes as an int once expanded.
+func interpolateNode(path string, node *yaml.Node, lookup lookupFunc) Problems {
+	var problems Problems
+	var walk func(n *yaml.Node)
+	walk = func(n *yaml.Node) {
+		switch n.Kind {
+		case yaml.DocumentNode, yaml.SequenceNode:
+			for _, c := range n.Content {
+				walk(c)
+			}
+		case yaml.MappingNode:
+			for i := 1; i < len(n.Content); i += 2 {
+				walk(n.Content[i])
+			}
+		case yaml.ScalarNode:
+			if !strings.Contains(n.Value, "$") {
+				return
+			}
+			value, err := interpolate(n.Value, lookup)
+			if err != nil {
+				pos := positionOf(n)
+				pos.File = path
+				problems = append(problems, Problem{Pos: pos, Message: err.Error()})
+				return
+			}
+			n.Value = value
+			if n.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle|yaml.LiteralStyle|yaml.FoldedStyle) == 0 {
+				n.Tag = ""
+			}
+		}
+	}
+	walk(node)
+	return problems
+}
+
+// interpolate expands ${VAR}, ${VAR:-default} and ${VAR:?message} in s.
+// "$$" produces a literal "$"; any other "$" is kept as is.
+func interpolate(s string, lookup lookupFunc) (string, error) {
+	var sb strings.Builder
+	for {
+		i := strings.IndexByte(s, '$')
+		if i < 0 || i == len(s)-1 {
+			sb.WriteString(s)
+			return sb.String(), nil
+		}
+		sb.WriteString(s[:i])
+		switch s[i+1] {
+		case '$':
+			sb.WriteByte('$')
+			s = s[i+2:]
+			continue
+		case '{':
+		default:
+			sb.WriteByte('$')
+			s = s[i+1:]
+			continue
+		}
+
+		end := strings.IndexByte(s[i:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated variable reference in %q", s[i:])
+		}
+		expr := s[i+2 : i+end]
+		s = s[i+end+1:]
+
+		value, err := expandVar(expr, lookup)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(value)
+	}
+}
+
+func expandVar(expr string, lookup lookupFunc) (string, error) {
+	name, op, arg := expr, "", ""
+	if i := strings.Index(expr, ":"); i >= 0 && i+1 < len(expr) && (expr[i+1] == '-' || expr[i+1] == '?') {
+		name, op, arg = expr[:i], expr[i:i+2], expr[i+2:]
+	}
+	if !validVarName(name) {
+		return "", fmt.Errorf("invalid variable reference ${%s}", expr)
+	}
+
+	value, ok := lookup(name)
+	switch op {
+	case ":-":
+		if !ok || value == "" {
+			return arg, nil
+		}
+	case ":?":
+		if !ok || value == "" {
+			if arg == "" {
+				arg = "is required"
+			}
+			return "", fmt.Errorf("variable %s: %s", name, arg)
+		}
+	}
+	return value, nil
+}
+
+func validVarName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r == '_', r >= 'A' && r <= 'Z', r >= 'a' && r <= 'z':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
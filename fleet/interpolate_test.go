@@ -0,0 +1,89 @@
+package fleet
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInterpolate(t *testing.T) {
+	lookup := func(name string) (string, bool) {
+		vars := map[string]string{"HOME": "/Users/me", "EMPTY": ""}
+		v, ok := vars[name]
+		return v, ok
+	}
+
+	tests := []struct {
+		in, want string
+	}{
+		{"${HOME}/Downloads/base.iso", "/Users/me/Downloads/base.iso"},
+		{"${MISSING}", ""},
+		{"${MISSING:-8GB}", "8GB"},
+		{"${EMPTY:-4}", "4"},
+		{"${HOME:-/tmp}", "/Users/me"},
+		{"cost: $$5 and $x", "cost: $5 and $x"},
+	}
+	for _, tt := range tests {
+		got, err := interpolate(tt.in, lookup)
+		if err != nil {
+			t.Fatalf("interpolate(%q) returned error: %v", tt.in, err)
+		}
+		if got != tt.want {
+			t.Errorf("interpolate(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	if _, err := interpolate("${ISO_DIR:?set ISO_DIR to your ISO folder}", lookup); err == nil ||
+		err.Error() != "variable ISO_DIR: set ISO_DIR to your ISO folder" {
+		t.Fatalf("interpolate(required) error = %v", err)
+	}
+	if _, err := interpolate("${HOME", lookup); err == nil {
+		t.Fatalf("interpolate(unterminated) returned no error")
+	}
+}
+
+func TestLoadConfigInterpolatesFromEnvAndDotEnv(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte("# local overrides\nexport DEV_CPU=6\nDEV_MEMORY=\"12GB\"\n"), 0o644); err != nil {
+		t.Fatalf("write .env: %v", err)
+	}
+	path := filepath.Join(dir, "fleet.yml")
+	if err := os.WriteFile(path, []byte(`vms:
+  dev:
+    cpu: ${DEV_CPU}
+    memory: ${DEV_MEMORY:-8GB}
+    image: ${LUME_FLEET_TEST_ISO}
+`), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	t.Setenv("DEV_CPU", "10")
+	t.Setenv("LUME_FLEET_TEST_ISO", "/isos/ubuntu.iso")
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() returned error: %v", err)
+	}
+	dev := cfg.VMs["dev"]
+	if dev.CPU != 10 {
+		t.Errorf("cpu = %d, want 10 from the environment", dev.CPU)
+	}
+	if dev.Memory != "12GB" {
+		t.Errorf("memory = %q, want 12GB from .env", dev.Memory)
+	}
+	if dev.Image != "/isos/ubuntu.iso" {
+		t.Errorf("image = %q, want /isos/ubuntu.iso", dev.Image)
+	}
+}
+
+func TestLoadConfigReportsUndefinedRequiredVariable(t *testing.T) {
+	path := writeConfig(t, `vms:
+  dev:
+    image: ${LUME_FLEET_UNSET_ISO:?point this at an Ubuntu ISO}
+`)
+
+	_, err := LoadConfig(path)
+	if err == nil || !strings.Contains(err.Error(), ":3:12: variable LUME_FLEET_UNSET_ISO: point this at an Ubuntu ISO") {
+		t.Fatalf("LoadConfig() error = %v, want required variable error with position", err)
+	}
+}
@@ -0,0 +1,475 @@
+package fleet
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadOptions selects the files that make up a fleet config.
+type LoadOptions struct {
+	// Paths are the base config files. The first one also locates fleet.d
+	// and the overlay files.
+	Paths []string
+	// Env names an overlay, fleet.<env>.yml, layered on top when set.
+	Env string
+
+	// contents holds unsaved file contents, keyed by absolute path, that are
+	// used instead of reading those files.
+	contents map[string][]byte
+}
+
+// LoadConfig reads and merges one or more fleet.yml files with no env
+// overlay. See Load.
+func LoadConfig(paths ...string) (*FleetConfig, error) {
+	return Load(LoadOptions{Paths: paths})
+}
+
+// Load reads and merges the config files selected by opts.
+//
+// Each file's include entries (paths or globs, relative to that file) are
+// loaded before the file itself, then the files are merged in the order
+// given, and then every fleet.d/*.yml next to the first file is merged in
+// name order. Later sources win for defaults; a VM or profile defined twice
+// is an error unless the later definition sets override: true.
+//
+// Finally fleet.override.yml, if present, and fleet.<env>.yml are layered on
+// top as overlays: their fields override existing VMs and profiles without
+// needing override: true, new entries are added, and entries set to null
+// are removed.
+//
+// ${VAR} references in values are expanded from the environment and an
+// optional .env file next to each config. Decoding is strict: unknown keys,
+// type errors and undefined required variables are all reported together
+// with their positions.
+func Load(opts LoadOptions) (*FleetConfig, error) {
+	cfg, problems, err := loadConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("fleet: invalid config %s:\n%w", quotePaths(opts.Paths), problems)
+	}
+	return cfg, nil
+}
+
+// loadConfig loads and merges the files selected by opts and returns the
+// result along with any strict-decoding or merge problems. cfg is nil only
+// when err is set.
+func loadConfig(opts LoadOptions) (*FleetConfig, Problems, error) {
+	paths := opts.Paths
+	if len(paths) == 0 {
+		return nil, nil, fmt.Errorf("fleet: no config file given")
+	}
+
+	l := &loader{loaded: map[string]bool{}, contents: opts.contents}
+	for _, path := range paths {
+		if err := l.load(path, nil); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	dropIns, err := filepath.Glob(filepath.Join(filepath.Dir(paths[0]), "fleet.d", "*.y*ml"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("fleet: list fleet.d: %w", err)
+	}
+	sort.Strings(dropIns)
+	for _, path := range dropIns {
+		if ext := filepath.Ext(path); ext != ".yml" && ext != ".yaml" {
+			continue
+		}
+		if err := l.load(path, nil); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	l.overlay = true
+	override := overlayPath(paths[0], "override")
+	if _, err := os.Stat(override); err == nil {
+		if err := l.load(override, nil); err != nil {
+			return nil, nil, err
+		}
+	}
+	if opts.Env != "" {
+		envPath := overlayPath(paths[0], opts.Env)
+		if _, err := os.Stat(envPath); err != nil {
+			return nil, nil, fmt.Errorf("fleet: env %q: %w", opts.Env, err)
+		}
+		if err := l.load(envPath, nil); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if len(l.cfg.VMs) == 0 {
+		return nil, nil, fmt.Errorf("fleet: no VMs defined in %s", quotePaths(paths))
+	}
+
+	l.problems.sort()
+	return &l.cfg, l.problems, nil
+}
+
+// loadFile loads path and the files it includes, but no fleet.d drop-ins or
+// overlays. data is used instead of reading path.
+func loadFile(path string, data []byte) (*FleetConfig, Problems, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fleet: resolve path %q: %w", path, err)
+	}
+	l := &loader{loaded: map[string]bool{}, contents: map[string][]byte{abs: data}}
+	if err := l.load(path, nil); err != nil {
+		return nil, nil, err
+	}
+	return &l.cfg, l.problems, nil
+}
+
+// overlayPath returns the overlay file for name next to base, e.g.
+// fleet.staging.yml for fleet.yml.
+func overlayPath(base, name string) string {
+	ext := filepath.Ext(base)
+	return strings.TrimSuffix(base, ext) + "." + name + ext
+}
+
+// loader merges config files into cfg in load order.
+type loader struct {
+	cfg      FleetConfig
+	problems Problems
+	loaded   map[string]bool
+	contents map[string][]byte
+	// overlay switches merging to overlay semantics for files loaded from
+	// now on.
+	overlay bool
+}
+
+// load merges the includes of path and then path itself. stack holds the
+// files currently being loaded, for include cycle detection.
+func (l *loader) load(path string, stack []string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("fleet: resolve path %q: %w", path, err)
+	}
+	if slices.Contains(stack, abs) {
+		return fmt.Errorf("fleet: include cycle: %s", strings.Join(append(stack, abs), " -> "))
+	}
+	if l.loaded[abs] {
+		return nil
+	}
+	l.loaded[abs] = true
+
+	data, ok := l.contents[abs]
+	if !ok {
+		data, err = os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("fleet: read config %q: %w", path, err)
+		}
+	}
+	cfg, problems, err := parseFile(path, data)
+	if err != nil {
+		return err
+	}
+	l.problems = append(l.problems, problems...)
+
+	for _, pattern := range cfg.Include {
+		includes, err := expandInclude(path, pattern)
+		if err != nil {
+			return err
+		}
+		for _, inc := range includes {
+			if err := l.load(inc, append(stack, abs)); err != nil {
+				return err
+			}
+		}
+	}
+
+	l.merge(cfg)
+	return nil
+}
+
+// expandInclude resolves an include entry relative to the including file.
+// Globs may match nothing; plain paths must exist.
+func expandInclude(from, pattern string) ([]string, error) {
+	pattern = expandHome(pattern)
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(filepath.Dir(from), pattern)
+	}
+	if !strings.ContainsAny(pattern, "*?[") {
+		if _, err := os.Stat(pattern); err != nil {
+			return nil, fmt.Errorf("fleet: include in %q: %w", from, err)
+		}
+		return []string{pattern}, nil
+	}
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("fleet: include %q in %q: %w", pattern, from, err)
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// merge folds cfg into the loader's config.
+func (l *loader) merge(cfg *FleetConfig) {
+	l.cfg.Warnings = append(l.cfg.Warnings, cfg.Warnings...)
+	l.cfg.Defaults = VMDefaults(mergeSpec(VMSpec(l.cfg.Defaults), VMSpec(cfg.Defaults)))
+	for key, raw := range cfg.rawDefaults {
+		if l.cfg.rawDefaults == nil {
+			l.cfg.rawDefaults = map[string]bool{}
+		}
+		l.cfg.rawDefaults[key] = raw
+	}
+	l.cfg.Profiles = l.mergeSpecs("profile", l.cfg.Profiles, cfg.Profiles, cfg.nulls["profiles"])
+	l.cfg.VMs = l.mergeSpecs("VM", l.cfg.VMs, cfg.VMs, cfg.nulls["vms"])
+	for _, name := range cfg.vmOrder {
+		if !slices.Contains(l.cfg.vmOrder, name) {
+			l.cfg.vmOrder = append(l.cfg.vmOrder, name)
+		}
+	}
+	if len(cfg.Sizes) > 0 {
+		if l.cfg.Sizes == nil {
+			l.cfg.Sizes = map[string]SizePreset{}
+		}
+		maps.Copy(l.cfg.Sizes, cfg.Sizes)
+	}
+	hv := reflect.ValueOf(cfg.Host)
+	outv := reflect.ValueOf(&l.cfg.Host).Elem()
+	for key, field := range yamlFields(reflect.TypeOf(HostSpec{})) {
+		if _, ok := cfg.Host.present[key]; ok || !hv.FieldByIndex(field.Index).IsZero() {
+			outv.FieldByIndex(field.Index).Set(hv.FieldByIndex(field.Index))
+		}
+	}
+	for key, pos := range cfg.Host.present {
+		if l.cfg.Host.present == nil {
+			l.cfg.Host.present = map[string]Position{}
+		}
+		l.cfg.Host.present[key] = pos
+	}
+}
+
+// mergeSpecs adds the specs in add to base. A name that is already defined
+// is reported unless the new definition is marked override or an overlay is
+// being loaded. Overlays also remove the entries named in nulls.
+func (l *loader) mergeSpecs(kind string, base, add map[string]VMSpec, nulls []string) map[string]VMSpec {
+	if len(add) == 0 {
+		return base
+	}
+	if base == nil {
+		base = map[string]VMSpec{}
+	}
+	for _, name := range slices.Sorted(maps.Keys(add)) {
+		spec := add[name]
+		existing, ok := base[name]
+		switch {
+		case l.overlay && slices.Contains(nulls, name):
+			delete(base, name)
+		case !ok:
+			base[name] = spec
+		case spec.Override || l.overlay:
+			base[name] = mergeSpec(existing, spec)
+		default:
+			l.problems = append(l.problems, Problem{
+				Pos:     spec.at,
+				Message: fmt.Sprintf("%s %q is already defined at %s (set override: true to replace it)", kind, name, existing.at),
+			})
+		}
+	}
+	return base
+}
+
+// mergeSpec returns base with every field set in over replaced by over's
+// value. ssh and labels are merged key by key and tags are added to base's
+// unless over sets replace-tags, the same way a VM inherits them. Presence
+// and positions are carried over so the result resolves exactly like over
+// layered on top of base.
+func mergeSpec(base, over VMSpec) VMSpec {
+	out := base
+	out.present = nil
+	if base.present != nil || over.present != nil {
+		out.present = map[string]Position{}
+	}
+
+	bv := reflect.ValueOf(base)
+	ov := reflect.ValueOf(over)
+	outv := reflect.ValueOf(&out).Elem()
+	for key, field := range yamlFields(reflect.TypeOf(VMSpec{})) {
+		switch {
+		case over.isSet(key, !ov.FieldByIndex(field.Index).IsZero()):
+			outv.FieldByIndex(field.Index).Set(ov.FieldByIndex(field.Index))
+			if out.present != nil {
+				out.present[key] = over.position(key)
+			}
+		case out.present != nil && base.isSet(key, !bv.FieldByIndex(field.Index).IsZero()):
+			out.present[key] = base.position(key)
+		}
+	}
+	if base.SSH != nil && over.SSH != nil {
+		ssh := base.SSH.merge(*over.SSH)
+		out.SSH = &ssh
+	}
+	if len(base.Labels) > 0 && len(over.Labels) > 0 {
+		out.Labels = maps.Clone(base.Labels)
+		maps.Copy(out.Labels, over.Labels)
+	}
+	if len(over.Tags) > 0 && !over.ReplaceTags {
+		out.Tags = mergeTags(base.Tags, over.Tags)
+	}
+	if over.present != nil {
+		out.at = over.at
+	}
+	return out
+}
+
+// parseFile decodes the contents of a single config file without following
+// includes.
+func parseFile(path string, data []byte) (*FleetConfig, Problems, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, fmt.Errorf("fleet: parse config %q: %w", path, err)
+	}
+	var cfg FleetConfig
+	if len(doc.Content) == 0 {
+		return &cfg, nil, nil
+	}
+	root := doc.Content[0]
+
+	applied, err := migrate(root)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fleet: %s: %w", path, err)
+	}
+
+	rawDefaults := map[string]bool{}
+	if defaults := mappingValue(root, "defaults"); defaults != nil && defaults.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(defaults.Content); i += 2 {
+			rawDefaults[defaults.Content[i].Value] = hasInterpolation(defaults.Content[i+1])
+		}
+	}
+
+	lookup, err := envLookup(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	problems := interpolateNode(path, root, lookup)
+	problems = append(problems, checkKeys(path, root, reflect.TypeOf(FleetConfig{}), "")...)
+
+	if err := root.Decode(&cfg); err != nil {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return nil, nil, fmt.Errorf("fleet: parse config %q: %w", path, err)
+		}
+		problems = append(problems, typeErrorProblems(path, typeErr)...)
+	}
+	cfg.setFile(path)
+	if len(applied) > 0 {
+		cfg.Warnings = append(cfg.Warnings, fmt.Sprintf(
+			"%s: config version %d is deprecated and was upgraded in memory to version %d; run `lume-fleet config migrate` to update the file",
+			path, applied[0].From, CurrentVersion))
+	}
+	cfg.Warnings = append(cfg.Warnings, sharedDirTagWarnings(path, &cfg)...)
+	cfg.vmOrder = blockKeys(root, "vms")
+	cfg.rawDefaults = rawDefaults
+	cfg.nulls = map[string][]string{
+		"profiles": nullEntries(root, "profiles"),
+		"vms":      nullEntries(root, "vms"),
+	}
+
+	return &cfg, problems, nil
+}
+
+// sharedDirTagWarnings reports the shared-dirs entries of a file that set a
+// tag, which lume run cannot pass on.
+func sharedDirTagWarnings(path string, cfg *FleetConfig) []string {
+	var warnings []string
+	check := func(block string, dirs []SharedDir) {
+		for _, d := range dirs {
+			if d.Tag != "" {
+				warnings = append(warnings, fmt.Sprintf("%s: %s: shared dir %q: tag %q is ignored; lume run does not support mount tags", path, block, d.Path, d.Tag))
+			}
+		}
+	}
+	check("defaults", cfg.Defaults.SharedDirs)
+	for _, name := range slices.Sorted(maps.Keys(cfg.Profiles)) {
+		check("profile "+strconv.Quote(name), cfg.Profiles[name].SharedDirs)
+	}
+	for _, name := range slices.Sorted(maps.Keys(cfg.VMs)) {
+		check("VM "+strconv.Quote(name), cfg.VMs[name].SharedDirs)
+	}
+	return warnings
+}
+
+// setFile records path as the source of every spec, size preset and host
+// key in the config.
+func (c *FleetConfig) setFile(path string) {
+	(*VMSpec)(&c.Defaults).setFile(path)
+	for _, specs := range []map[string]VMSpec{c.Profiles, c.VMs} {
+		for name, spec := range specs {
+			spec.setFile(path)
+			specs[name] = spec
+		}
+	}
+	for name, preset := range c.Sizes {
+		preset.at.File = path
+		c.Sizes[name] = preset
+	}
+	for key, pos := range c.Host.present {
+		pos.File = path
+		c.Host.present[key] = pos
+	}
+}
+
+// blockKeys returns the names under the top-level block key in file order.
+func blockKeys(root *yaml.Node, key string) []string {
+	block := mappingValue(root, key)
+	if block == nil || block.Kind != yaml.MappingNode {
+		return nil
+	}
+	var names []string
+	for i := 0; i+1 < len(block.Content); i += 2 {
+		if name := block.Content[i].Value; name != "<<" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// nullEntries returns the names under the top-level block key whose value
+// is an explicit null, such as "dev-main: null" or "dev-main: ~".
+func nullEntries(root *yaml.Node, key string) []string {
+	block := mappingValue(root, key)
+	if block == nil || block.Kind != yaml.MappingNode {
+		return nil
+	}
+	var names []string
+	for i := 0; i+1 < len(block.Content); i += 2 {
+		if v := block.Content[i+1]; v.Kind == yaml.ScalarNode && v.ShortTag() == "!!null" {
+			names = append(names, block.Content[i].Value)
+		}
+	}
+	return names
+}
+
+// mappingValue returns the value for key in a mapping node, or nil.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func quotePaths(paths []string) string {
+	quoted := make([]string, len(paths))
+	for i, p := range paths {
+		quoted[i] = fmt.Sprintf("%q", p)
+	}
+	return strings.Join(quoted, ", ")
+}
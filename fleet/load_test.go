@@ -0,0 +1,199 @@
+package fleet
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+func TestLoadConfigMergesIncludesConfigsAndDropIns(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"shared/base.yml": `defaults:
+  cpu: 2
+  memory: 4GB
+vms:
+  ci-runner-1:
+    os: linux
+`,
+		"fleet.yml": `include: [shared/*.yml]
+defaults:
+  cpu: 4
+vms:
+  dev-main:
+    memory: 16GB
+`,
+		"personal.yml": `vms:
+  scratch: {}
+`,
+		"fleet.d/10-me.yml": `vms:
+  dev-main:
+    override: true
+    cpu: 12
+`,
+	})
+
+	cfg, err := LoadConfig(filepath.Join(dir, "fleet.yml"), filepath.Join(dir, "personal.yml"))
+	if err != nil {
+		t.Fatalf("LoadConfig() returned error: %v", err)
+	}
+
+	for _, name := range []string{"ci-runner-1", "dev-main", "scratch"} {
+		if _, ok := cfg.VMs[name]; !ok {
+			t.Errorf("VM %q missing from merged config", name)
+		}
+	}
+	if cfg.Defaults.CPU != 4 || cfg.Defaults.Memory != "4GB" {
+		t.Errorf("defaults = cpu %d memory %q, want fleet.yml cpu 4 over included memory 4GB", cfg.Defaults.CPU, cfg.Defaults.Memory)
+	}
+
+	dev := cfg.VMs["dev-main"]
+	if dev.CPU != 12 || dev.Memory != "16GB" {
+		t.Errorf("dev-main = cpu %d memory %q, want fleet.d cpu 12 and original memory 16GB", dev.CPU, dev.Memory)
+	}
+}
+
+func TestLoadConfigRejectsConflictingVMsWithoutOverride(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"fleet.yml": `vms:
+  dev-main:
+    cpu: 8
+`,
+		"fleet.d/me.yml": `vms:
+  dev-main:
+    cpu: 12
+`,
+	})
+
+	_, err := LoadConfig(filepath.Join(dir, "fleet.yml"))
+	if err == nil || !strings.Contains(err.Error(), `me.yml:3:5: VM "dev-main" is already defined at `) {
+		t.Fatalf("LoadConfig() error = %v, want conflicting VM definition", err)
+	}
+}
+
+func TestLoadConfigDetectsIncludeCycles(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.yml": "include: [b.yml]\nvms: {a: {}}\n",
+		"b.yml": "include: [a.yml]\n",
+	})
+
+	_, err := LoadConfig(filepath.Join(dir, "a.yml"))
+	if err == nil || !strings.Contains(err.Error(), "include cycle") {
+		t.Fatalf("LoadConfig() error = %v, want include cycle", err)
+	}
+}
+
+func TestLoadAppliesOverrideAndEnvOverlays(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"fleet.yml": `defaults:
+  memory: 8GB
+vms:
+  dev-main:
+    cpu: 8
+    memory: 16GB
+  ci-runner-1:
+    os: linux
+  ci-runner-2:
+    os: linux
+`,
+		"fleet.override.yml": `vms:
+  dev-main:
+    vnc-port: 5901
+`,
+		"fleet.laptop.yml": `defaults:
+  memory: 4GB
+vms:
+  dev-main:
+    cpu: 4
+  ci-runner-2: null
+  scratch:
+    os: linux
+`,
+	})
+	base := filepath.Join(dir, "fleet.yml")
+
+	cfg, err := Load(LoadOptions{Paths: []string{base}, Env: "laptop"})
+	if err != nil {
+		t.Fatalf("Load() returned error: %v", err)
+	}
+
+	if _, ok := cfg.VMs["ci-runner-2"]; ok {
+		t.Errorf("ci-runner-2 should have been removed by the null overlay entry")
+	}
+	if _, ok := cfg.VMs["scratch"]; !ok {
+		t.Errorf("scratch should have been added by the overlay")
+	}
+	dev := cfg.VMs["dev-main"]
+	if dev.CPU != 4 || dev.Memory != "16GB" || dev.VNCPort != 5901 {
+		t.Errorf("dev-main = cpu %d memory %q vnc-port %d, want 4, 16GB, 5901", dev.CPU, dev.Memory, dev.VNCPort)
+	}
+	if cfg.Defaults.Memory != "4GB" {
+		t.Errorf("defaults memory = %q, want 4GB from the overlay", cfg.Defaults.Memory)
+	}
+
+	if _, err := Load(LoadOptions{Paths: []string{base}, Env: "studio"}); err == nil {
+		t.Fatalf("Load() with a missing env overlay returned no error")
+	}
+}
+
+func TestLoadOverlayMergesNestedValues(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"fleet.yml": `host:
+  cpu: 12
+  macos-limit: 3
+vms:
+  dev-main:
+    tags: [dev]
+    labels:
+      team: ios
+      tier: dev
+    ssh:
+      user: lume
+      identity-file: ~/.ssh/dev
+`,
+		"fleet.override.yml": `host:
+  macos-limit: 0
+vms:
+  dev-main:
+    tags: [gpu]
+    labels:
+      tier: prod
+    ssh:
+      user: admin
+`,
+	})
+
+	cfg, err := Load(LoadOptions{Paths: []string{filepath.Join(dir, "fleet.yml")}})
+	if err != nil {
+		t.Fatalf("Load() returned error: %v", err)
+	}
+
+	dev := cfg.VMs["dev-main"]
+	if dev.SSH == nil || dev.SSH.User != "admin" || dev.SSH.IdentityFile != "~/.ssh/dev" {
+		t.Errorf("dev-main ssh = %+v, want user admin with identity-file ~/.ssh/dev", dev.SSH)
+	}
+	if dev.Labels["team"] != "ios" || dev.Labels["tier"] != "prod" {
+		t.Errorf("dev-main labels = %v, want team ios and tier prod", dev.Labels)
+	}
+	if got := strings.Join(dev.Tags, ","); got != "dev,gpu" {
+		t.Errorf("dev-main tags = %s, want dev,gpu", got)
+	}
+	if cfg.Host.CPU != 12 || cfg.Host.MacOSLimit != 0 {
+		t.Errorf("host = cpu %d macos-limit %d, want 12, 0", cfg.Host.CPU, cfg.Host.MacOSLimit)
+	}
+}
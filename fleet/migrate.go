@@ -0,0 +1,140 @@
+package fleet
+
+import (
+	"fmt"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CurrentVersion is the config schema version written by this lume-fleet.
+// Files without a version key are version 1; they only need migrating if
+// they use something a later version changed.
+const CurrentVersion = 2
+
+// Migration upgrades a config document from one schema version to the next
+// by rewriting its node tree in place. Apply reports whether it changed
+// anything.
+type Migration struct {
+	From        int
+	Description string
+	Apply       func(root *yaml.Node) (bool, error)
+}
+
+// migrations holds one entry per schema version below CurrentVersion, in
+// order.
+var migrations = []Migration{
+	{
+		From:        1,
+		Description: "shared-dir strings become shared-dirs entries",
+		Apply:       migrateSharedDir,
+	},
+}
+
+// documentVersion returns the schema version declared in root.
+func documentVersion(root *yaml.Node) (int, error) {
+	node := mappingValue(root, "version")
+	if node == nil {
+		return 1, nil
+	}
+	v, err := strconv.Atoi(node.Value)
+	if err != nil || v < 1 {
+		return 0, fmt.Errorf("invalid version %q", node.Value)
+	}
+	return v, nil
+}
+
+// migrate upgrades root to CurrentVersion and returns the migrations that
+// changed it. The version key is only written when something changed, so
+// older files that need no rewriting, and fragments such as drop-ins and
+// overlays that carry no version, load as they are. Documents from a newer
+// lume-fleet are rejected.
+func migrate(root *yaml.Node) ([]Migration, error) {
+	if root.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	version, err := documentVersion(root)
+	if err != nil {
+		return nil, err
+	}
+	if version > CurrentVersion {
+		return nil, fmt.Errorf("config version %d is newer than this lume-fleet supports (%d); upgrade lume-fleet", version, CurrentVersion)
+	}
+
+	var applied []Migration
+	for _, m := range migrations {
+		if m.From < version {
+			continue
+		}
+		changed, err := m.Apply(root)
+		if err != nil {
+			return applied, fmt.Errorf("migrate from version %d: %w", m.From, err)
+		}
+		if changed {
+			applied = append(applied, m)
+		}
+	}
+	if len(applied) > 0 {
+		setVersion(root, CurrentVersion)
+	}
+	return applied, nil
+}
+
+// setVersion sets the version key, adding it as the first key if missing.
+func setVersion(root *yaml.Node, version int) {
+	value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(version)}
+	if node := mappingValue(root, "version"); node != nil {
+		replaceNode(node, value)
+		return
+	}
+	key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "version"}
+	if len(root.Content) > 0 {
+		// Keep the file's leading comment at the top.
+		key.HeadComment, root.Content[0].HeadComment = root.Content[0].HeadComment, ""
+	}
+	root.Content = append([]*yaml.Node{key, value}, root.Content...)
+}
+
+// migrateSharedDir turns "shared-dir: PATH" into a leading
+// "shared-dirs: [{path: PATH}]" entry in defaults, profiles and VMs.
+func migrateSharedDir(root *yaml.Node) (bool, error) {
+	changed := false
+	forEachSpec(root, func(_ string, spec *yaml.Node) {
+		for i := 0; i+1 < len(spec.Content); i += 2 {
+			key, legacy := spec.Content[i], spec.Content[i+1]
+			if key.Value != "shared-dir" {
+				continue
+			}
+			changed = true
+			entry := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{
+				{Kind: yaml.ScalarNode, Tag: "!!str", Value: "path"},
+				legacy,
+			}}
+
+			dirs := mappingValue(spec, "shared-dirs")
+			if dirs == nil {
+				// Rename in place so the key keeps its position and comments.
+				key.Value = "shared-dirs"
+				spec.Content[i+1] = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: []*yaml.Node{entry}}
+				return
+			}
+			if dirs.Kind != yaml.SequenceNode {
+				*dirs = yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			}
+			dirs.Content = append([]*yaml.Node{entry}, dirs.Content...)
+			spec.Content = append(spec.Content[:i], spec.Content[i+2:]...)
+			return
+		}
+	})
+	return changed, nil
+}
+
+// Migrate upgrades the document to CurrentVersion and returns the
+// migrations that changed it. Comments and ordering are kept.
+func (d *Document) Migrate() ([]Migration, error) {
+	applied, err := migrate(d.root())
+	if err != nil {
+		return nil, fmt.Errorf("fleet: %s: %w", d.path, err)
+	}
+	return applied, nil
+}
@@ -0,0 +1,133 @@
+package fleet
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadMigratesLegacySharedDir(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfig(t, `defaults:
+  shared-dir: `+dir+`
+vms:
+  dev:
+    shared-dir: `+dir+`
+    shared-dirs:
+      - path: `+dir+`
+        read-only: true
+`)
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() returned error: %v", err)
+	}
+	if len(cfg.Warnings) != 1 || !strings.Contains(cfg.Warnings[0], "config migrate") {
+		t.Fatalf("Warnings = %v, want one deprecation warning", cfg.Warnings)
+	}
+
+	want := []SharedDir{{Path: dir}, {Path: dir, ReadOnly: true}}
+	if got := cfg.VMs["dev"].SharedDirs; !reflect.DeepEqual(got, want) {
+		t.Fatalf("dev shared-dirs = %+v, want %+v", got, want)
+	}
+	if got := cfg.Defaults.SharedDirs; !reflect.DeepEqual(got, want[:1]) {
+		t.Fatalf("defaults shared-dirs = %+v, want %+v", got, want[:1])
+	}
+}
+
+func TestLoadRejectsNewerVersion(t *testing.T) {
+	path := writeConfig(t, "version: 99\nvms: {}\n")
+
+	_, err := LoadConfig(path)
+	if err == nil || !strings.Contains(err.Error(), "newer than this lume-fleet supports") {
+		t.Fatalf("LoadConfig() error = %v, want newer version error", err)
+	}
+}
+
+func TestLoadCurrentVersionHasNoWarnings(t *testing.T) {
+	path := writeConfig(t, "version: 2\nvms:\n  dev: {}\n")
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() returned error: %v", err)
+	}
+	if len(cfg.Warnings) != 0 {
+		t.Fatalf("Warnings = %v, want none", cfg.Warnings)
+	}
+}
+
+func TestLoadUnversionedFilesWithoutLegacyFieldsHaveNoWarnings(t *testing.T) {
+	path := writeConfig(t, "vms:\n  dev:\n    cpu: 4\n")
+	overlay := filepath.Join(filepath.Dir(path), "fleet.override.yml")
+	if err := os.WriteFile(overlay, []byte("vms:\n  dev:\n    cpu: 8\n"), 0o644); err != nil {
+		t.Fatalf("write overlay: %v", err)
+	}
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() returned error: %v", err)
+	}
+	if len(cfg.Warnings) != 0 {
+		t.Fatalf("Warnings = %v, want none", cfg.Warnings)
+	}
+
+	doc, err := OpenDocument(path)
+	if err != nil {
+		t.Fatalf("OpenDocument() returned error: %v", err)
+	}
+	if applied, err := doc.Migrate(); err != nil || len(applied) != 0 {
+		t.Fatalf("Migrate() = %v, %v, want nothing applied", applied, err)
+	}
+	if changed, err := doc.Changed(); err != nil || changed {
+		t.Fatalf("Changed() = %v, %v, want the file untouched", changed, err)
+	}
+}
+
+func TestDocumentMigrateKeepsComments(t *testing.T) {
+	path := writeConfig(t, `# team fleet
+vms:
+  dev:
+    # host checkout
+    shared-dir: ~/Projects
+    cpu: 8
+`)
+
+	doc, err := OpenDocument(path)
+	if err != nil {
+		t.Fatalf("OpenDocument() returned error: %v", err)
+	}
+	applied, err := doc.Migrate()
+	if err != nil {
+		t.Fatalf("Migrate() returned error: %v", err)
+	}
+	if len(applied) != 1 || applied[0].From != 1 {
+		t.Fatalf("Migrate() applied %+v, want the version 1 migration", applied)
+	}
+	if err := doc.Save(); err != nil {
+		t.Fatalf("Save() returned error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# team fleet
+version: 2
+vms:
+  dev:
+    # host checkout
+    shared-dirs:
+      - path: ~/Projects
+    cpu: 8
+`
+	if string(data) != want {
+		t.Fatalf("migrated file =\n%s\nwant\n%s", data, want)
+	}
+
+	applied, err = doc.Migrate()
+	if err != nil || len(applied) != 0 {
+		t.Fatalf("second Migrate() = %v, %v, want nothing applied", applied, err)
+	}
+}
@@ -0,0 +1,71 @@
+package fleet
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// layer is one source of VM settings, applied in order during Resolve.
+type layer struct {
+	spec   VMSpec
+	origin string // "" for the VM's own settings
+}
+
+// layers returns the settings applied to a VM, lowest precedence first:
+// built-in defaults, the defaults block, each extended profile in order
+// (with its own extends applied before it), then the VM spec itself.
+func (c *FleetConfig) layers(spec VMSpec) ([]layer, error) {
+	if len(c.Defaults.Extends) > 0 {
+		return nil, fmt.Errorf("defaults cannot use extends")
+	}
+
+	out := []layer{
+		{spec: builtinDefaults, origin: "built-in defaults"},
+		{spec: VMSpec(c.Defaults), origin: "defaults"},
+	}
+	for _, name := range spec.Extends {
+		profiles, err := c.expandProfile(name, nil)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, profiles...)
+	}
+	return append(out, layer{spec: spec}), nil
+}
+
+// expandProfile returns the layers for profile name, preceded by the layers
+// of every profile it extends. chain holds the profiles already being
+// expanded and is used for cycle detection and error messages.
+func (c *FleetConfig) expandProfile(name string, chain []string) ([]layer, error) {
+	chain = append(slices.Clone(chain), name)
+	if slices.Contains(chain[:len(chain)-1], name) {
+		return nil, fmt.Errorf("profile cycle: %s", strings.Join(chain, " -> "))
+	}
+
+	profile, ok := c.Profiles[name]
+	if !ok {
+		if len(chain) > 1 {
+			return nil, fmt.Errorf("unknown profile %q (extends chain: %s)", name, strings.Join(chain, " -> "))
+		}
+		return nil, fmt.Errorf("unknown profile %q", name)
+	}
+
+	var out []layer
+	for _, parent := range profile.Extends {
+		parents, err := c.expandProfile(parent, chain)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, parents...)
+	}
+	return append(out, layer{spec: profile, origin: "profile " + strings.Join(chain, " -> ")}), nil
+}
+
+// fromOrigin describes where a resolved field came from, for error messages.
+func fromOrigin(origins map[string]string, key string) string {
+	if origin := origins[key]; origin != "" {
+		return " (from " + origin + ")"
+	}
+	return ""
+}
@@ -0,0 +1,80 @@
+package fleet
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResolveAppliesProfilesInOrder(t *testing.T) {
+	cfg := FleetConfig{
+		Defaults: VMDefaults{CPU: 2, Memory: "4GB"},
+		Profiles: map[string]VMSpec{
+			"base":        {DiskSize: "80GB", Tags: []string{"managed"}},
+			"mac-builder": {Extends: []string{"base"}, CPU: 8, Memory: "16GB", Tags: []string{"builder"}},
+			"big-disk":    {DiskSize: "200GB"},
+		},
+		VMs: map[string]VMSpec{
+			"builder-1": {Extends: []string{"mac-builder", "big-disk"}, Memory: "24GB"},
+		},
+	}
+
+	resolved, err := cfg.Resolve()
+	if err != nil {
+		t.Fatalf("Resolve() returned error: %v", err)
+	}
+
+	vm := resolved[0]
+	if vm.CPU != 8 {
+		t.Fatalf("CPU = %d, want 8 from mac-builder", vm.CPU)
+	}
+	if vm.Memory != "24GB" {
+		t.Fatalf("Memory = %q, want VM override 24GB", vm.Memory)
+	}
+	if vm.DiskSize != "200GB" {
+		t.Fatalf("DiskSize = %q, want 200GB from the later big-disk profile", vm.DiskSize)
+	}
+	if want := []string{"managed", "builder"}; !reflect.DeepEqual(vm.Tags, want) {
+		t.Fatalf("Tags = %v, want %v", vm.Tags, want)
+	}
+}
+
+func TestResolveDetectsProfileCycles(t *testing.T) {
+	cfg := FleetConfig{
+		Profiles: map[string]VMSpec{
+			"a": {Extends: []string{"b"}},
+			"b": {Extends: []string{"a"}},
+		},
+		VMs: map[string]VMSpec{
+			"vm": {Extends: []string{"a"}},
+		},
+	}
+
+	_, err := cfg.Resolve()
+	if err == nil || !strings.Contains(err.Error(), "profile cycle: a -> b -> a") {
+		t.Fatalf("Resolve() error = %v, want profile cycle a -> b -> a", err)
+	}
+}
+
+func TestResolveErrorsNameProfileChain(t *testing.T) {
+	cfg := FleetConfig{
+		Profiles: map[string]VMSpec{
+			"base":        {Memory: "lots"},
+			"mac-builder": {Extends: []string{"base"}},
+		},
+		VMs: map[string]VMSpec{
+			"vm": {Extends: []string{"mac-builder"}},
+		},
+	}
+
+	_, err := cfg.Resolve()
+	if err == nil || !strings.Contains(err.Error(), "from profile mac-builder -> base") {
+		t.Fatalf("Resolve() error = %v, want mention of profile mac-builder -> base", err)
+	}
+
+	cfg.Profiles["mac-builder"] = VMSpec{Extends: []string{"missing"}}
+	_, err = cfg.Resolve()
+	if err == nil || !strings.Contains(err.Error(), `unknown profile "missing" (extends chain: mac-builder -> missing)`) {
+		t.Fatalf("Resolve() error = %v, want unknown profile with chain", err)
+	}
+}
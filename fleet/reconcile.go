@@ -1,6 +1,9 @@
 package fleet
 
 import (
+	"cmp"
+	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/hoalong/lume-fleet/lume"
@@ -15,6 +18,7 @@ const (
 	ActionNoop                      // VM exists, running -> skip
 	ActionStop                      // stop a running VM
 	ActionDestroy                   // delete the VM entirely
+	ActionSkip                      // VM should run but does not fit the limits
 )
 
 // Action represents a reconciliation step.
@@ -22,10 +26,46 @@ type Action struct {
 	VM      ResolvedVM
 	Type    ActionType
 	Current *lume.VM // nil if VM doesn't exist yet
+	// Reason says why a VM is skipped, or why PlanUp stops it.
+	Reason string
+	// For names the VM a stop planned by PlanUp makes room for.
+	For string
 }
 
-// PlanUp compares desired VMs against actual Lume state and returns actions.
-func PlanUp(desired []ResolvedVM, actual []lume.VM) []Action {
+// UpLimits are what PlanUp schedules the VMs it creates and starts against.
+type UpLimits struct {
+	// Budget is the host capacity; nil is not limited. PlanUp reserves the
+	// resources of the VMs it plans to run.
+	Budget *Budget
+	// MacOSLimit is how many macOS VMs may run at once; 0 is not limited.
+	MacOSLimit int
+	// Preempt lets a macOS VM over the limit stop the running macOS VM of
+	// Fleet with the lowest priority below its own.
+	Preempt bool
+	Fleet   []ResolvedVM
+}
+
+// UpLimits returns the limits of this host given the VMs in actual, with
+// fleetVMs as the VMs preemption may stop. warning is set when the host
+// memory cannot be detected.
+func (c *FleetConfig) UpLimits(fleetVMs []ResolvedVM, actual []lume.VM, preempt bool) (limits UpLimits, warning string, err error) {
+	capacity, warning, err := c.HostCapacity()
+	if err != nil {
+		return UpLimits{}, "", err
+	}
+	return UpLimits{
+		Budget:     NewBudget(capacity, actual, c.VMNames()),
+		MacOSLimit: c.MacOSLimit(),
+		Preempt:    preempt,
+		Fleet:      fleetVMs,
+	}, warning, nil
+}
+
+// PlanUp compares desired VMs against actual Lume state and returns actions,
+// highest priority first. VMs to create or start that do not fit limits
+// get ActionSkip; with limits.Preempt, the stops that make room for a VM
+// come right before it.
+func PlanUp(desired []ResolvedVM, actual []lume.VM, limits UpLimits) []Action {
 	index := indexByName(actual)
 	var actions []Action
 
@@ -48,7 +88,79 @@ func PlanUp(desired []ResolvedVM, actual []lume.VM) []Action {
 		}
 	}
 
-	return actions
+	SortByPriority(actions)
+	return limits.schedule(actions, actual)
+}
+
+// schedule checks the actions that run a VM against the limits, in order,
+// as if every earlier action succeeded.
+func (l UpLimits) schedule(actions []Action, actual []lume.VM) []Action {
+	actual = slices.Clone(actual)
+	macosRunning := CountRunningMacOS(actual)
+	preempted := map[string]bool{}
+
+	var plan []Action
+	for _, a := range actions {
+		if preempted[a.VM.Name] {
+			continue
+		}
+		if a.Type != ActionCreate && a.Type != ActionStart {
+			plan = append(plan, a)
+			continue
+		}
+
+		macos := strings.EqualFold(a.VM.OS, "macos")
+		if macos && l.MacOSLimit > 0 && macosRunning >= l.MacOSLimit {
+			victim, ok := PreemptCandidate(l.Fleet, actual, a.VM.Priority)
+			if !l.Preempt || !ok {
+				a.Type, a.Reason = ActionSkip, fmt.Sprintf("macOS %d-VM concurrent limit reached", l.MacOSLimit)
+				plan = append(plan, a)
+				continue
+			}
+			// Only stop the victim if the VM then fits the budget.
+			if err := l.reserve(a, victim.Name); err != nil {
+				a.Type, a.Reason = ActionSkip, err.Error()
+				plan = append(plan, a)
+				continue
+			}
+			i := slices.IndexFunc(actual, func(vm lume.VM) bool { return vm.Name == victim.Name })
+			current := actual[i]
+			plan = append(plan, Action{
+				VM: victim, Type: ActionStop, Current: &current, For: a.VM.Name,
+				Reason: fmt.Sprintf("free a macOS slot for %s (priority %d < %d)", a.VM.Name, victim.Priority, a.VM.Priority),
+			})
+			actual[i].Status = "stopped"
+			preempted[victim.Name] = true
+			macosRunning--
+		} else if err := l.reserve(a, ""); err != nil {
+			a.Type, a.Reason = ActionSkip, err.Error()
+			plan = append(plan, a)
+			continue
+		}
+		if macos {
+			macosRunning++
+		}
+		plan = append(plan, a)
+	}
+	return plan
+}
+
+// reserve claims the budget for the VM of a, with the resources of the VM
+// named release returned first. The budget is left unchanged when the VM
+// does not fit.
+func (l UpLimits) reserve(a Action, release string) error {
+	if l.Budget == nil {
+		return nil
+	}
+	consumers := slices.Clone(l.Budget.Consumers)
+	if release != "" {
+		l.Budget.Release(release)
+	}
+	if err := l.Budget.Reserve(a.VM, a.Current); err != nil {
+		l.Budget.Consumers = consumers
+		return err
+	}
+	return nil
 }
 
 // PlanDown returns stop actions for matching VMs that are running.
@@ -81,6 +193,46 @@ func PlanDestroy(desired []ResolvedVM, actual []lume.VM) []Action {
 	return actions
 }
 
+// DefaultMacOSLimit is how many macOS VMs Apple's virtualization framework
+// lets one host run at once.
+const DefaultMacOSLimit = 2
+
+// MacOSLimit returns how many macOS VMs may run at once: host.macos-limit,
+// or DefaultMacOSLimit when it is not set.
+func (c *FleetConfig) MacOSLimit() int {
+	if c.Host.MacOSLimit > 0 {
+		return c.Host.MacOSLimit
+	}
+	return DefaultMacOSLimit
+}
+
+// SortByPriority orders actions by descending VM priority. Actions with the
+// same priority keep their order.
+func SortByPriority(actions []Action) {
+	slices.SortStableFunc(actions, func(a, b Action) int {
+		return cmp.Compare(b.VM.Priority, a.VM.Priority)
+	})
+}
+
+// PreemptCandidate returns the running macOS VM of the fleet with the
+// lowest priority below priority, so that it can be stopped to make room.
+// Ties are broken by name.
+func PreemptCandidate(fleetVMs []ResolvedVM, actual []lume.VM, priority int) (ResolvedVM, bool) {
+	index := indexByName(actual)
+	var victim ResolvedVM
+	found := false
+	for _, vm := range fleetVMs {
+		current, ok := index[vm.Name]
+		if !ok || !strings.EqualFold(current.OS, "macos") || !strings.EqualFold(current.Status, "running") || vm.Priority >= priority {
+			continue
+		}
+		if !found || vm.Priority < victim.Priority || (vm.Priority == victim.Priority && vm.Name < victim.Name) {
+			victim, found = vm, true
+		}
+	}
+	return victim, found
+}
+
 // CountRunningMacOS counts how many macOS VMs are currently running.
 func CountRunningMacOS(actual []lume.VM) int {
 	count := 0
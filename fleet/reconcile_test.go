@@ -0,0 +1,151 @@
+package fleet
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hoalong/lume-fleet/lume"
+)
+
+func TestSortByPriority(t *testing.T) {
+	actions := []Action{
+		{VM: ResolvedVM{Name: "a"}},
+		{VM: ResolvedVM{Name: "b", Priority: 10}},
+		{VM: ResolvedVM{Name: "c"}},
+		{VM: ResolvedVM{Name: "d", Priority: -1}},
+		{VM: ResolvedVM{Name: "e", Priority: 10}},
+	}
+
+	SortByPriority(actions)
+
+	var got []string
+	for _, a := range actions {
+		got = append(got, a.VM.Name)
+	}
+	if want := "b e a c d"; strings.Join(got, " ") != want {
+		t.Fatalf("SortByPriority() order = %s, want %s", strings.Join(got, " "), want)
+	}
+}
+
+func TestPreemptCandidate(t *testing.T) {
+	fleetVMs := []ResolvedVM{
+		{Name: "mac-ci", Priority: 1},
+		{Name: "mac-scratch", Priority: -5},
+		{Name: "mac-dev", Priority: 50},
+		{Name: "linux-low", Priority: -10},
+		{Name: "mac-stopped", Priority: -20},
+	}
+	actual := []lume.VM{
+		{Name: "mac-ci", OS: "macOS", Status: "running"},
+		{Name: "mac-scratch", OS: "macos", Status: "running"},
+		{Name: "mac-dev", OS: "macos", Status: "running"},
+		{Name: "linux-low", OS: "linux", Status: "running"},
+		{Name: "mac-stopped", OS: "macos", Status: "stopped"},
+		{Name: "not-in-fleet", OS: "macos", Status: "running"},
+	}
+
+	tests := []struct {
+		priority int
+		want     string
+	}{
+		{priority: 100, want: "mac-scratch"},
+		{priority: 0, want: "mac-scratch"},
+		{priority: -5, want: ""},
+	}
+	for _, tt := range tests {
+		got, ok := PreemptCandidate(fleetVMs, actual, tt.priority)
+		if tt.want == "" {
+			if ok {
+				t.Errorf("PreemptCandidate(%d) = %s, want none", tt.priority, got.Name)
+			}
+			continue
+		}
+		if !ok || got.Name != tt.want {
+			t.Errorf("PreemptCandidate(%d) = %s, %v, want %s", tt.priority, got.Name, ok, tt.want)
+		}
+	}
+}
+
+func TestPlanUpSchedulesAgainstLimits(t *testing.T) {
+	desired := []ResolvedVM{
+		{Name: "mac-dev", OS: "macos", CPU: 4, Memory: "8GB", Autostart: true, Priority: 10},
+		{Name: "mac-ci", OS: "macos", CPU: 4, Memory: "8GB", Autostart: true},
+		{Name: "linux-big", OS: "linux", CPU: 16, Memory: "8GB", Autostart: true, Priority: 5},
+		{Name: "mac-scratch", OS: "macos", Autostart: true, Priority: -5},
+	}
+	actual := []lume.VM{
+		{Name: "mac-scratch", OS: "macos", Status: "running", CPUCount: 2, MemorySize: 4 * gb},
+		{Name: "mac-ci", OS: "macos", Status: "stopped", CPUCount: 4, MemorySize: 8 * gb},
+	}
+	limits := func(preempt bool) UpLimits {
+		return UpLimits{
+			Budget:     NewBudget(Capacity{CPU: 12, MemoryMB: 32 * 1024}, actual, []string{"mac-dev", "mac-ci", "linux-big", "mac-scratch"}),
+			MacOSLimit: 2,
+			Preempt:    preempt,
+			Fleet:      desired,
+		}
+	}
+	plan := func(actions []Action) string {
+		var steps []string
+		for _, a := range actions {
+			step := a.VM.Name + ":" + map[ActionType]string{
+				ActionCreate: "create", ActionStart: "start", ActionNoop: "noop", ActionStop: "stop", ActionSkip: "skip",
+			}[a.Type]
+			if a.For != "" {
+				step += ">" + a.For
+			}
+			steps = append(steps, step)
+		}
+		return strings.Join(steps, " ")
+	}
+
+	got := PlanUp(desired, actual, limits(false))
+	if want := "mac-dev:create linux-big:skip mac-ci:skip mac-scratch:noop"; plan(got) != want {
+		t.Fatalf("PlanUp() = %s, want %s", plan(got), want)
+	}
+	if !strings.Contains(got[1].Reason, "host capacity exceeded") || !strings.Contains(got[2].Reason, "macOS 2-VM concurrent limit reached") {
+		t.Fatalf("PlanUp() reasons = %q, %q, want capacity and macOS limit", got[1].Reason, got[2].Reason)
+	}
+
+	got = PlanUp(desired, actual, limits(true))
+	if want := "mac-dev:create linux-big:skip mac-scratch:stop>mac-ci mac-ci:start"; plan(got) != want {
+		t.Fatalf("PlanUp(preempt) = %s, want %s", plan(got), want)
+	}
+	if actual[0].Status != "running" {
+		t.Fatalf("PlanUp() changed actual")
+	}
+}
+
+func TestPlanUpDoesNotPreemptWhenTheVMStillDoesNotFit(t *testing.T) {
+	desired := []ResolvedVM{
+		{Name: "mac-big", OS: "macos", CPU: 16, Memory: "8GB", Autostart: true, Priority: 10},
+		{Name: "mac-scratch", OS: "macos", Autostart: true, Priority: -5},
+	}
+	actual := []lume.VM{
+		{Name: "mac-scratch", OS: "macos", Status: "running", CPUCount: 2, MemorySize: 4 * gb},
+	}
+	budget := NewBudget(Capacity{CPU: 8, MemoryMB: 32 * 1024}, actual, []string{"mac-big", "mac-scratch"})
+	limits := UpLimits{Budget: budget, MacOSLimit: 1, Preempt: true, Fleet: desired}
+
+	got := PlanUp(desired, actual, limits)
+	if len(got) != 2 || got[0].Type != ActionSkip || got[1].Type != ActionNoop {
+		t.Fatalf("PlanUp() = %+v, want mac-big skipped and mac-scratch left running", got)
+	}
+	if !strings.Contains(got[0].Reason, "host capacity exceeded") {
+		t.Fatalf("PlanUp() reason = %q, want host capacity exceeded", got[0].Reason)
+	}
+	if used := budget.Used(); used != (Capacity{CPU: 2, MemoryMB: 4 * 1024}) {
+		t.Fatalf("budget used = %+v, want only mac-scratch", used)
+	}
+}
+
+func TestMacOSLimit(t *testing.T) {
+	cfg := FleetConfig{}
+	if got := cfg.MacOSLimit(); got != DefaultMacOSLimit {
+		t.Fatalf("MacOSLimit() = %d, want default %d", got, DefaultMacOSLimit)
+	}
+	cfg.Host.MacOSLimit = 1
+	if got := cfg.MacOSLimit(); got != 1 {
+		t.Fatalf("MacOSLimit() = %d, want 1", got)
+	}
+}
@@ -1,9 +1,13 @@
 package fleet
 
 import (
+	"cmp"
 	"fmt"
+	"maps"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
 )
 
@@ -11,114 +15,290 @@ import (
 type ResolvedVM struct {
 	Name       string
 	OS         string
+	Size       string
 	CPU        int
 	Memory     string
 	DiskSize   string
-	SharedDir  string
+	SharedDirs []SharedDir
 	Unattended string
 	VNCPort    int
 	Image      string
 	Storage    string
 	Tags       []string
+	Labels     map[string]string
 	Autostart  bool
+	SSH        SSHSpec
+	Priority   int
+	Order      int
 }
 
-// Resolve merges defaults into each VM spec and returns a sorted list.
+// builtinDefaults are applied underneath the defaults block.
+var builtinDefaults = VMSpec{
+	OS:       "macos",
+	CPU:      4,
+	Memory:   "8GB",
+	DiskSize: "50GB",
+	SSH:      &SSHSpec{User: "lume"},
+}
+
+// Resolve merges defaults into each VM spec and returns the VMs sorted by
+// their order weight, then in config order. All invalid VMs are reported
+// together as Problems.
 func (c *FleetConfig) Resolve() ([]ResolvedVM, error) {
 	var vms []ResolvedVM
+	var problems Problems
 
-	for name, spec := range c.VMs {
-		vm := ResolvedVM{
-			Name:      name,
-			OS:        coalesce(spec.OS, c.Defaults.OS, "macos"),
-			CPU:       coalesceInt(spec.CPU, c.Defaults.CPU, 4),
-			Memory:    coalesce(spec.Memory, c.Defaults.Memory, "8GB"),
-			DiskSize:  coalesce(spec.DiskSize, c.Defaults.DiskSize, "50GB"),
-			SharedDir: expandHome(spec.SharedDir),
-			VNCPort:   coalesceInt(spec.VNCPort, c.Defaults.VNCPort, 0),
-			Image:     expandHome(coalesce(spec.Image, c.Defaults.Image, "")),
-			Storage:   coalesce(spec.Storage, c.Defaults.Storage, ""),
-			Tags:      spec.Tags,
-			Autostart: true,
+	for _, name := range c.VMNames() {
+		vm, _, errs := c.resolveVM(name, c.VMs[name])
+		if len(errs) > 0 {
+			problems = append(problems, errs...)
+			continue
 		}
+		vms = append(vms, vm)
+	}
 
-		// Only apply unattended default for macOS VMs
-		if strings.EqualFold(vm.OS, "macos") {
-			vm.Unattended = coalesce(spec.Unattended, c.Defaults.Unattended, "")
-		}
+	if len(problems) > 0 {
+		problems.sort()
+		return nil, problems
+	}
+	slices.SortStableFunc(vms, func(a, b ResolvedVM) int { return cmp.Compare(a.Order, b.Order) })
+	return vms, nil
+}
 
-		if spec.Autostart != nil {
-			vm.Autostart = *spec.Autostart
-		}
+// provenance records which layer set each resolved field of a VM.
+type provenance struct {
+	origins   map[string]string
+	positions map[string]Position
+	at        Position
+}
 
-		// Validate memory and disk-size are parseable
-		if _, err := ParseSize(vm.Memory); err != nil {
-			return nil, fmt.Errorf("VM %q: invalid memory: %w", name, err)
-		}
-		if _, err := ParseSize(vm.DiskSize); err != nil {
-			return nil, fmt.Errorf("VM %q: invalid disk-size: %w", name, err)
+// position returns where key was set, falling back to the VM itself.
+func (p provenance) position(key string) Position {
+	if pos, ok := p.positions[key]; ok && pos.Line > 0 {
+		return pos
+	}
+	return p.at
+}
+
+// resolveVM applies every layer to spec and checks the result. The VM is
+// returned even when problems are found so that callers can keep checking it.
+func (c *FleetConfig) resolveVM(name string, spec VMSpec) (ResolvedVM, provenance, Problems) {
+	prov := provenance{
+		origins:   map[string]string{},
+		positions: map[string]Position{},
+		at:        spec.at,
+	}
+	var problems Problems
+	add := func(key, format string, args ...any) {
+		problems = append(problems, Problem{
+			Pos:     prov.position(key),
+			Message: fmt.Sprintf("VM %q: ", name) + fmt.Sprintf(format, args...),
+		})
+	}
+
+	layers, err := c.layers(spec)
+	if err != nil {
+		add("extends", "%v", err)
+		return ResolvedVM{}, prov, problems
+	}
+
+	vm := ResolvedVM{Name: name, Autostart: true}
+	for _, l := range layers {
+		// A size expands into its preset before the layer's own fields, so
+		// cpu, memory and disk-size set next to it still win.
+		if l.spec.isSet("size", l.spec.Size != "") {
+			vm.Size = l.spec.Size
+			prov.origins["size"] = l.origin
+			prov.positions["size"] = l.spec.position("size")
+			preset, ok := c.SizePreset(l.spec.Size)
+			if !ok {
+				add("size", "unknown size %q%s", l.spec.Size, fromOrigin(prov.origins, "size"))
+			}
+			origin := "size " + l.spec.Size
+			if l.origin != "" {
+				origin += " via " + l.origin
+			}
+			for _, key := range vm.apply(preset.spec()) {
+				prov.origins[key] = origin
+				prov.positions[key] = l.spec.position("size")
+			}
 		}
-		if vm.VNCPort < 0 || vm.VNCPort > 65535 {
-			return nil, fmt.Errorf("VM %q: invalid vnc-port %d (must be 0-65535)", name, vm.VNCPort)
+		for _, key := range vm.apply(l.spec) {
+			prov.origins[key] = l.origin
+			prov.positions[key] = l.spec.position(key)
 		}
+	}
 
-		vms = append(vms, vm)
+	// Only apply unattended for macOS VMs
+	if !strings.EqualFold(vm.OS, "macos") {
+		vm.Unattended = ""
 	}
+	vm.Image = expandHome(vm.Image)
+	vm.SSH.IdentityFile = expandHome(vm.SSH.IdentityFile)
 
-	return vms, nil
+	sharedDirs, err := resolveSharedDirs(vm.SharedDirs)
+	if err != nil {
+		add("shared-dirs", "%v%s", err, fromOrigin(prov.origins, "shared-dirs"))
+	}
+	vm.SharedDirs = sharedDirs
+
+	// Validate memory and disk-size are parseable
+	if _, err := ParseSize(vm.Memory); err != nil {
+		add("memory", "invalid memory%s: %v", fromOrigin(prov.origins, "memory"), err)
+	}
+	if _, err := ParseSize(vm.DiskSize); err != nil {
+		add("disk-size", "invalid disk-size%s: %v", fromOrigin(prov.origins, "disk-size"), err)
+	}
+	if vm.VNCPort < 0 || vm.VNCPort > 65535 {
+		add("vnc-port", "invalid vnc-port %d%s (must be 0-65535)", vm.VNCPort, fromOrigin(prov.origins, "vnc-port"))
+	}
+	if vm.SSH.Port < 0 || vm.SSH.Port > 65535 {
+		add("ssh", "invalid ssh port %d%s (must be 0-65535)", vm.SSH.Port, fromOrigin(prov.origins, "ssh"))
+	}
+
+	return vm, prov, problems
 }
 
-// FilterByNames returns only VMs whose names are in the given list.
+// FilterByNames returns only VMs whose names match one of the given names
+// or glob patterns such as "ci-runner-*".
 func FilterByNames(vms []ResolvedVM, names []string) []ResolvedVM {
 	if len(names) == 0 {
 		return vms
 	}
-	set := make(map[string]bool, len(names))
-	for _, n := range names {
-		set[n] = true
-	}
 	var result []ResolvedVM
 	for _, vm := range vms {
-		if set[vm.Name] {
-			result = append(result, vm)
+		for _, n := range names {
+			if ok, _ := path.Match(n, vm.Name); ok || n == vm.Name {
+				result = append(result, vm)
+				break
+			}
 		}
 	}
 	return result
 }
 
-// FilterByTag returns only VMs that have the given tag.
-func FilterByTag(vms []ResolvedVM, tag string) []ResolvedVM {
-	if tag == "" {
+// FilterByTags returns only VMs that have at least one of the given tags.
+func FilterByTags(vms []ResolvedVM, tags []string) []ResolvedVM {
+	if len(tags) == 0 {
 		return vms
 	}
 	var result []ResolvedVM
 	for _, vm := range vms {
-		for _, t := range vm.Tags {
-			if t == tag {
-				result = append(result, vm)
-				break
-			}
+		if slices.ContainsFunc(tags, func(t string) bool { return slices.Contains(vm.Tags, t) }) {
+			result = append(result, vm)
 		}
 	}
 	return result
 }
 
-func coalesce(values ...string) string {
-	for _, v := range values {
-		if v != "" {
-			return v
+// resolveSharedDirs expands ~ in each shared directory and checks that the
+// host path exists. The input slice is left untouched since it may be shared
+// with the defaults block.
+func resolveSharedDirs(in []SharedDir) ([]SharedDir, error) {
+	if len(in) == 0 {
+		return nil, nil
+	}
+	dirs := append([]SharedDir(nil), in...)
+	for i := range dirs {
+		if dirs[i].Path == "" {
+			return nil, fmt.Errorf("shared-dirs[%d]: path is required", i)
 		}
+		dirs[i].Path = expandHome(dirs[i].Path)
+		info, err := os.Stat(dirs[i].Path)
+		if err != nil {
+			return nil, fmt.Errorf("shared dir %q: %w", dirs[i].Path, err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("shared dir %q is not a directory", dirs[i].Path)
+		}
+	}
+	return dirs, nil
+}
+
+// apply overlays every field set in s onto vm and returns the keys it set.
+// Tags are merged unless s asks to replace them and labels are merged key by
+// key; all other fields are replaced.
+func (vm *ResolvedVM) apply(s VMSpec) []string {
+	var keys []string
+	set := func(key string, ok bool) bool {
+		if ok {
+			keys = append(keys, key)
+		}
+		return ok
+	}
+
+	if set("os", s.isSet("os", s.OS != "")) {
+		vm.OS = s.OS
+	}
+	if set("cpu", s.isSet("cpu", s.CPU != 0)) {
+		vm.CPU = s.CPU
+	}
+	if set("memory", s.isSet("memory", s.Memory != "")) {
+		vm.Memory = s.Memory
+	}
+	if set("disk-size", s.isSet("disk-size", s.DiskSize != "")) {
+		vm.DiskSize = s.DiskSize
+	}
+	if set("unattended", s.isSet("unattended", s.Unattended != "")) {
+		vm.Unattended = s.Unattended
+	}
+	if set("image", s.isSet("image", s.Image != "")) {
+		vm.Image = s.Image
+	}
+	if set("vnc-port", s.isSet("vnc-port", s.VNCPort != 0)) {
+		vm.VNCPort = s.VNCPort
+	}
+	if set("storage", s.isSet("storage", s.Storage != "")) {
+		vm.Storage = s.Storage
+	}
+
+	if set("shared-dirs", s.isSet("shared-dirs", len(s.SharedDirs) > 0)) {
+		vm.SharedDirs = s.SharedDirs
+	}
+
+	if set("tags", s.isSet("tags", len(s.Tags) > 0) || s.ReplaceTags) {
+		if s.ReplaceTags {
+			vm.Tags = nil
+		}
+		vm.Tags = mergeTags(vm.Tags, s.Tags)
+	}
+
+	if set("labels", s.isSet("labels", len(s.Labels) > 0)) {
+		labels := maps.Clone(vm.Labels)
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		maps.Copy(labels, s.Labels)
+		vm.Labels = labels
+	}
+
+	if set("ssh", s.SSH != nil) {
+		vm.SSH = vm.SSH.merge(*s.SSH)
+	}
+
+	if set("priority", s.isSet("priority", s.Priority != 0)) {
+		vm.Priority = s.Priority
+	}
+
+	if set("order", s.isSet("order", s.Order != 0)) {
+		vm.Order = s.Order
+	}
+
+	if set("autostart", s.Autostart != nil) {
+		vm.Autostart = *s.Autostart
 	}
-	return ""
+	return keys
 }
 
-func coalesceInt(values ...int) int {
-	for _, v := range values {
-		if v != 0 {
-			return v
+// mergeTags appends the tags in add that are not already in base.
+func mergeTags(base, add []string) []string {
+	merged := append([]string(nil), base...)
+	for _, t := range add {
+		if !slices.Contains(merged, t) {
+			merged = append(merged, t)
 		}
 	}
-	return 0
+	return merged
 }
 
 func expandHome(path string) string {
@@ -1,8 +1,13 @@
 package fleet
 
 import (
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+
+	"gopkg.in/yaml.v3"
 )
 
 func TestResolveInheritsAndOverridesVNCPort(t *testing.T) {
@@ -80,3 +85,192 @@ func TestResolveInheritsAndExpandsImagePath(t *testing.T) {
 		t.Fatalf("mac-override image = %q, want expanded ~/Downloads/macos.ipsw", gotByName["mac-override"].Image)
 	}
 }
+
+func TestResolveExplicitZeroValuesOverrideDefaults(t *testing.T) {
+	var cfg FleetConfig
+	src := `
+defaults:
+  vnc-port: 6100
+  image: latest
+  autostart: false
+vms:
+  inherits: {}
+  explicit:
+    vnc-port: 0
+    image: ""
+    autostart: true
+`
+	if err := yaml.Unmarshal([]byte(src), &cfg); err != nil {
+		t.Fatalf("yaml.Unmarshal() returned error: %v", err)
+	}
+
+	resolved, err := cfg.Resolve()
+	if err != nil {
+		t.Fatalf("Resolve() returned error: %v", err)
+	}
+
+	gotByName := map[string]ResolvedVM{}
+	for _, vm := range resolved {
+		gotByName[vm.Name] = vm
+	}
+
+	inherits := gotByName["inherits"]
+	if inherits.VNCPort != 6100 || inherits.Image != "latest" || inherits.Autostart {
+		t.Fatalf("inherits = %+v, want vnc-port 6100, image latest, autostart false", inherits)
+	}
+
+	explicit := gotByName["explicit"]
+	if explicit.VNCPort != 0 || explicit.Image != "" || !explicit.Autostart {
+		t.Fatalf("explicit = %+v, want vnc-port 0, empty image, autostart true", explicit)
+	}
+}
+
+func TestResolveMergesAndReplacesTags(t *testing.T) {
+	cfg := FleetConfig{
+		Defaults: VMDefaults{Tags: []string{"fleet", "dev"}},
+		VMs: map[string]VMSpec{
+			"merged":   {Tags: []string{"dev", "ios"}},
+			"replaced": {Tags: []string{"ci"}, ReplaceTags: true},
+			"cleared":  {ReplaceTags: true},
+		},
+	}
+
+	resolved, err := cfg.Resolve()
+	if err != nil {
+		t.Fatalf("Resolve() returned error: %v", err)
+	}
+
+	gotByName := map[string]ResolvedVM{}
+	for _, vm := range resolved {
+		gotByName[vm.Name] = vm
+	}
+
+	if got, want := gotByName["merged"].Tags, []string{"fleet", "dev", "ios"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("merged tags = %v, want %v", got, want)
+	}
+	if got, want := gotByName["replaced"].Tags, []string{"ci"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("replaced tags = %v, want %v", got, want)
+	}
+	if got := gotByName["cleared"].Tags; len(got) != 0 {
+		t.Fatalf("cleared tags = %v, want none", got)
+	}
+}
+
+func TestResolveInheritsSharedDirsFromDefaults(t *testing.T) {
+	dir := t.TempDir()
+	cfg := FleetConfig{
+		Defaults: VMDefaults{SharedDirs: []SharedDir{{Path: dir, ReadOnly: true}}},
+		VMs: map[string]VMSpec{
+			"vm": {},
+		},
+	}
+
+	resolved, err := cfg.Resolve()
+	if err != nil {
+		t.Fatalf("Resolve() returned error: %v", err)
+	}
+	if got := resolved[0].SharedDirs; len(got) != 1 || got[0].Path != dir || !got[0].ReadOnly {
+		t.Fatalf("shared dirs = %+v, want read-only %s", got, dir)
+	}
+}
+
+func TestResolveSharedDirsRejectsMissingAndNonDirectoryPaths(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file.txt")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		dirs []SharedDir
+		want string
+	}{
+		{[]SharedDir{{Path: dir}, {Path: ""}}, "shared-dirs[1]: path is required"},
+		{[]SharedDir{{Path: filepath.Join(dir, "missing")}}, "no such file or directory"},
+		{[]SharedDir{{Path: file}}, "is not a directory"},
+	}
+	for _, tt := range tests {
+		_, err := resolveSharedDirs(tt.dirs)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("resolveSharedDirs(%+v) error = %v, want %q", tt.dirs, err, tt.want)
+		}
+	}
+}
+
+func TestLoadWarnsAboutSharedDirTags(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfig(t, `version: 2
+vms:
+  dev:
+    shared-dirs:
+      - path: `+dir+`
+        tag: build-cache
+`)
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() returned error: %v", err)
+	}
+	if len(cfg.Warnings) != 1 || !strings.Contains(cfg.Warnings[0], `tag "build-cache" is ignored`) {
+		t.Fatalf("Warnings = %v, want one ignored-tag warning", cfg.Warnings)
+	}
+}
+
+func TestResolveKeepsConfigOrderAndOrderWeights(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"fleet.yml": `include: [base.yml]
+profiles:
+  late:
+    order: 10
+vms:
+  zeta: {}
+  alpha:
+    extends: [late]
+  mid: {}
+  first:
+    order: -1
+`,
+		"base.yml": `vms:
+  from-include: {}
+`,
+		"fleet.d/extra.yml": `vms:
+  beta: {}
+`,
+	})
+
+	cfg, err := LoadConfig(filepath.Join(dir, "fleet.yml"))
+	if err != nil {
+		t.Fatalf("LoadConfig() returned error: %v", err)
+	}
+	want := []string{"first", "from-include", "zeta", "mid", "beta", "alpha"}
+	// Resolve must not depend on map iteration order.
+	for range 10 {
+		resolved, err := cfg.Resolve()
+		if err != nil {
+			t.Fatalf("Resolve() returned error: %v", err)
+		}
+		var got []string
+		for _, vm := range resolved {
+			got = append(got, vm.Name)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("Resolve() order = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestResolveSortsVMsBuiltInCodeByName(t *testing.T) {
+	cfg := FleetConfig{VMs: map[string]VMSpec{"c": {}, "a": {}, "b": {Order: -1}}}
+
+	resolved, err := cfg.Resolve()
+	if err != nil {
+		t.Fatalf("Resolve() returned error: %v", err)
+	}
+	var got []string
+	for _, vm := range resolved {
+		got = append(got, vm.Name)
+	}
+	if want := []string{"b", "a", "c"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Resolve() order = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,286 @@
+package fleet
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+)
+
+// sizePattern matches the size strings accepted by ParseSize.
+const sizePattern = `^\s*[0-9]+(\.[0-9]+)?\s*[MmGgTt][Bb]\s*$`
+
+// interpolationPattern matches values containing a ${VAR} reference, which
+// are only checked after expansion.
+const interpolationPattern = `\$\{`
+
+// fieldSchemas adds descriptions and constraints to the schema generated
+// from the config structs, keyed by yaml key. Entries replace the generated
+// keywords of the same name, and every yaml field needs an entry.
+var fieldSchemas = map[string]map[string]any{
+	// FleetConfig
+	"version": {
+		"description": "Config schema version. Files without it are version 1.",
+		"minimum":     1,
+		"maximum":     CurrentVersion,
+	},
+	"include": {
+		"description": "Other config files or globs to merge, relative to this file.",
+	},
+	"defaults": {
+		"description": "Values inherited by every VM.",
+	},
+	"profiles": {
+		"description":          "Named, reusable sets of VM fields referenced with extends. In overlays, null removes a profile.",
+		"additionalProperties": nullable("VMSpec"),
+	},
+	"vms": {
+		"description":          "VMs in the fleet, by name. In overlays, null removes a VM.",
+		"additionalProperties": nullable("VMSpec"),
+	},
+	"host": {
+		"description": "Host capacity up schedules VMs against, where unset values are detected, and host tools.",
+	},
+	"macos-limit": {
+		"description": "How many macOS VMs may run at once.",
+		"minimum":     1,
+		"default":     DefaultMacOSLimit,
+	},
+	"overcommit": {
+		"description":      "Ratio scaling host CPUs and memory into the VM budget, e.g. 1.5.",
+		"exclusiveMinimum": 0,
+	},
+	"vnc-viewer": {
+		"description": "Command vnc --open runs. {url} is replaced with the VNC URL, which is appended otherwise. Defaults to open on macOS and xdg-open elsewhere.",
+		"examples":    []string{"open -a \"Screen Sharing\" {url}", "vncviewer"},
+	},
+	"sizes": {
+		"description": "Size presets, by name. Fields replace those of the built-in preset with the same name.",
+	},
+
+	// VMSpec
+	"os": {
+		"description": "Guest operating system, macos or linux in any case.",
+		// The enum is what editors complete; the pattern accepts any case.
+		"anyOf": []any{
+			map[string]any{"enum": []string{"macos", "linux"}},
+			map[string]any{"pattern": `^([Mm][Aa][Cc][Oo][Ss]|[Ll][Ii][Nn][Uu][Xx])$|` + interpolationPattern},
+		},
+	},
+	"size": {
+		"description": "Size preset setting cpu, memory and disk-size: small, medium, large, xl or a name from sizes.",
+		"examples":    []string{"small", "medium", "large", "xl"},
+	},
+	"cpu": {
+		"description": "Number of CPUs.",
+		"minimum":     1,
+	},
+	"memory": {
+		"description": "Memory size, e.g. 8GB or 512MB.",
+		"pattern":     sizePattern,
+	},
+	"disk-size": {
+		"description": "Disk size, e.g. 50GB.",
+		"pattern":     sizePattern,
+	},
+	"shared-dirs": {
+		"description": "Host directories shared into the VM when it runs.",
+	},
+	"unattended": {
+		"description": "macOS unattended setup preset or file. Ignored for Linux VMs.",
+	},
+	"image": {
+		"description": "macOS IPSW path or latest, or Linux ISO path.",
+	},
+	"vnc-port": {
+		"description": "VNC port. 0 picks a free port.",
+		"minimum":     0,
+		"maximum":     65535,
+	},
+	"storage": {
+		"description": "Named lume storage location.",
+	},
+	"tags": {
+		"description": "Tags for filtering. Added to inherited tags unless replace-tags is set.",
+		"items":       map[string]any{"type": "string", "pattern": tagPattern.String()},
+	},
+	"labels": {
+		"description":          "Key/value labels for --selector, merged key by key with inherited labels.",
+		"propertyNames":        map[string]any{"pattern": tagPattern.String()},
+		"additionalProperties": map[string]any{"type": "string", "pattern": "^$|" + tagPattern.String()},
+	},
+	"autostart": {
+		"description": "Set false to keep the VM created but stopped on up.",
+	},
+	"ssh": {
+		"description": "How lume-fleet ssh, exec and cp connect to the VM. Merged field by field with inherited blocks.",
+	},
+	"priority": {
+		"description": "Higher priority VMs are started first when the macOS limit is reached, and may preempt lower ones with up --preempt.",
+	},
+	"order": {
+		"description": "Sort weight. VMs are listed and processed by ascending order, then in config order.",
+	},
+	"extends": {
+		"description": "Profiles applied, in order, before this spec.",
+	},
+	"override": {
+		"description": "Replace fields of a VM or profile with the same name from an earlier file.",
+	},
+	"replace-tags": {
+		"description": "Replace inherited tags instead of adding to them.",
+	},
+
+	// SSHSpec
+	"user": {
+		"description": "SSH user (default: lume).",
+	},
+	"identity-file": {
+		"description": "Private key passed to ssh -i. ~/ is expanded.",
+	},
+	"port": {
+		"description": "SSH port (default: 22).",
+		"minimum":     1,
+		"maximum":     65535,
+	},
+	"options": {
+		"description": "ssh -o options, e.g. StrictHostKeyChecking: \"no\". Merged key by key with inherited options.",
+	},
+
+	// SharedDir
+	"path": {
+		"description": "Host directory. ~/ is expanded.",
+		"minLength":   1,
+	},
+	"read-only": {
+		"description": "Share the directory read-only.",
+	},
+	"tag": {
+		"description": "Mount tag inside the guest. Not supported by lume run yet; ignored with a warning.",
+	},
+}
+
+// schemaOmit lists fields that are accepted by a struct's yaml tags but not
+// supported in that block.
+var schemaOmit = map[reflect.Type][]string{
+	reflect.TypeFor[VMDefaults](): {"extends"},
+}
+
+// schemaLiteral lists keys read before ${VAR} interpolation, which therefore
+// do not accept it.
+var schemaLiteral = []string{"version"}
+
+// Schema returns a JSON Schema for fleet.yml files, generated from the
+// config structs so that editors can complete and check them.
+func Schema() ([]byte, error) {
+	defs := map[string]any{}
+	root, err := structSchema(reflect.TypeFor[FleetConfig](), defs)
+	if err != nil {
+		return nil, err
+	}
+	root["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	root["title"] = "lume-fleet config"
+	root["$defs"] = defs
+
+	data, err := json.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// typeSchema returns the schema for values of type t. Struct types other
+// than the root are added to defs and referenced.
+func typeSchema(t reflect.Type, defs map[string]any) (map[string]any, error) {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return typeSchema(t.Elem(), defs)
+	case reflect.String:
+		return map[string]any{"type": "string"}, nil
+	case reflect.Int:
+		return map[string]any{"type": "integer"}, nil
+	case reflect.Float64:
+		return map[string]any{"type": "number"}, nil
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}, nil
+	case reflect.Slice:
+		items, err := typeSchema(t.Elem(), defs)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "array", "items": items}, nil
+	case reflect.Map:
+		values, err := typeSchema(t.Elem(), defs)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "object", "additionalProperties": values}, nil
+	case reflect.Struct:
+		if _, ok := defs[t.Name()]; !ok {
+			defs[t.Name()] = nil // reserve against recursion
+			def, err := structSchema(t, defs)
+			if err != nil {
+				return nil, err
+			}
+			defs[t.Name()] = def
+		}
+		return map[string]any{"$ref": "#/$defs/" + t.Name()}, nil
+	}
+	return nil, fmt.Errorf("fleet: no schema for %s", t)
+}
+
+// structSchema returns an object schema with one property per yaml field.
+func structSchema(t reflect.Type, defs map[string]any) (map[string]any, error) {
+	props := map[string]any{}
+	for name, f := range yamlFields(t) {
+		if slices.Contains(schemaOmit[t], name) {
+			continue
+		}
+		extra, ok := fieldSchemas[name]
+		if !ok {
+			return nil, fmt.Errorf("fleet: no schema description for %s.%s (%q)", t.Name(), f.Name, name)
+		}
+		prop, err := typeSchema(f.Type, defs)
+		if err != nil {
+			return nil, err
+		}
+		maps.Copy(prop, extra)
+		if !slices.Contains(schemaLiteral, name) {
+			prop = interpolatable(prop)
+		}
+		props[name] = prop
+	}
+	return map[string]any{
+		"type":                 "object",
+		"properties":           props,
+		"additionalProperties": false,
+	}, nil
+}
+
+// interpolatable returns a copy of schema that also accepts strings with a
+// ${VAR} reference where it expects a number, a boolean or a string matching
+// a pattern, including in array items and map values.
+func interpolatable(schema map[string]any) map[string]any {
+	schema = maps.Clone(schema)
+	switch typ := schema["type"]; typ {
+	case "integer", "number", "boolean":
+		schema["type"] = []any{typ, "string"}
+		schema["pattern"] = interpolationPattern
+	case "string":
+		if pattern, ok := schema["pattern"].(string); ok {
+			schema["pattern"] = pattern + "|" + interpolationPattern
+		}
+	}
+	for _, key := range []string{"items", "additionalProperties"} {
+		if sub, ok := schema[key].(map[string]any); ok {
+			schema[key] = interpolatable(sub)
+		}
+	}
+	return schema
+}
+
+// nullable returns a schema accepting the named definition or null.
+func nullable(def string) map[string]any {
+	return map[string]any{"anyOf": []any{map[string]any{"$ref": "#/$defs/" + def}, map[string]any{"type": "null"}}}
+}
@@ -0,0 +1,131 @@
+package fleet
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestSchemaMatchesCommittedFile(t *testing.T) {
+	got, err := Schema()
+	if err != nil {
+		t.Fatalf("Schema() returned error: %v", err)
+	}
+	want, err := os.ReadFile("../fleet.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Fatalf("fleet.schema.json is out of date; run `go run . schema > fleet.schema.json`")
+	}
+}
+
+func TestSchemaCoversEveryField(t *testing.T) {
+	data, err := Schema()
+	if err != nil {
+		t.Fatalf("Schema() returned error: %v", err)
+	}
+	var schema struct {
+		Properties map[string]any `json:"properties"`
+		Defs       map[string]struct {
+			Properties map[string]map[string]any `json:"properties"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("Schema() is not valid JSON: %v", err)
+	}
+
+	for key := range yamlFields(reflect.TypeFor[FleetConfig]()) {
+		if _, ok := schema.Properties[key]; !ok {
+			t.Errorf("schema has no top-level property %q", key)
+		}
+	}
+	for _, typ := range []reflect.Type{reflect.TypeFor[VMSpec](), reflect.TypeFor[VMDefaults](), reflect.TypeFor[SharedDir](), reflect.TypeFor[SizePreset](), reflect.TypeFor[HostSpec](), reflect.TypeFor[SSHSpec]()} {
+		def, ok := schema.Defs[typ.Name()]
+		if !ok {
+			t.Fatalf("schema has no definition for %s", typ.Name())
+		}
+		for key := range yamlFields(typ) {
+			prop, ok := def.Properties[key]
+			if !ok {
+				if typ.Name() == "VMDefaults" && key == "extends" {
+					continue
+				}
+				t.Errorf("%s schema has no property %q", typ.Name(), key)
+				continue
+			}
+			if prop["description"] == nil {
+				t.Errorf("%s.%s has no description", typ.Name(), key)
+			}
+		}
+	}
+
+	if _, ok := schema.Defs["VMDefaults"].Properties["extends"]; ok {
+		t.Errorf("VMDefaults schema allows extends")
+	}
+	if got := schema.Defs["VMSpec"].Properties["cpu"]["type"]; !reflect.DeepEqual(got, []any{"integer", "string"}) {
+		t.Errorf("cpu type = %v, want [integer string] for ${VAR} values", got)
+	}
+	if got := schema.Properties["version"].(map[string]any)["type"]; got != "integer" {
+		t.Errorf("version type = %v, want integer", got)
+	}
+	nullVM := map[string]any{"anyOf": []any{map[string]any{"$ref": "#/$defs/VMSpec"}, map[string]any{"type": "null"}}}
+	for _, key := range []string{"vms", "profiles"} {
+		if got := schema.Properties[key].(map[string]any)["additionalProperties"]; !reflect.DeepEqual(got, nullVM) {
+			t.Errorf("%s entries = %v, want a VMSpec or null", key, got)
+		}
+	}
+}
+
+func TestSchemaPatterns(t *testing.T) {
+	data, err := Schema()
+	if err != nil {
+		t.Fatalf("Schema() returned error: %v", err)
+	}
+	var schema struct {
+		Defs map[string]struct {
+			Properties map[string]map[string]any `json:"properties"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("Schema() is not valid JSON: %v", err)
+	}
+
+	tests := []struct {
+		field string
+		value string
+		want  bool
+	}{
+		{"os", "macos", true},
+		{"os", "macOS", true},
+		{"os", "Linux", true},
+		{"os", "windows", false},
+		{"os", "${GUEST_OS}", true},
+		{"memory", "8GB", true},
+		{"memory", "8 gigs", false},
+		{"memory", "${MEMORY:-8GB}", true},
+		{"cpu", "${CPUS}", true},
+		{"cpu", "four", false},
+	}
+	for _, tt := range tests {
+		prop := schema.Defs["VMSpec"].Properties[tt.field]
+		pattern, _ := prop["pattern"].(string)
+		if anyOf, ok := prop["anyOf"].([]any); ok {
+			pattern, _ = anyOf[len(anyOf)-1].(map[string]any)["pattern"].(string)
+		}
+		if got := regexp.MustCompile(pattern).MatchString(tt.value); got != tt.want {
+			t.Errorf("%s pattern %q matches %q = %v, want %v", tt.field, pattern, tt.value, got, tt.want)
+		}
+	}
+
+	anyOf, _ := schema.Defs["VMSpec"].Properties["os"]["anyOf"].([]any)
+	if len(anyOf) == 0 {
+		t.Fatalf("os schema has no anyOf")
+	}
+	enum, _ := anyOf[0].(map[string]any)["enum"].([]any)
+	if !reflect.DeepEqual(enum, []any{"macos", "linux"}) {
+		t.Errorf("os enum = %v, want [macos linux]", enum)
+	}
+}
@@ -0,0 +1,287 @@
+package fleet
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Selector matches resolved VMs against a label/tag expression.
+//
+// Grammar, loosest binding first:
+//
+//	expr  = and { "||" and }
+//	and   = unary { ( "&&" | "," ) unary }
+//	unary = "!" unary | "(" expr ")" | term
+//	term  = key [ ( "=" | "==" | "!=" ) value | ( "in" | "notin" ) "(" value { "," value } ")" ]
+//
+// A bare key matches VMs that have it as a tag or a label. Comparisons look
+// the key up in the VM's labels, falling back to the built-in keys "name"
+// and "os".
+type Selector interface {
+	Matches(vm ResolvedVM) bool
+}
+
+// ParseSelector parses a selector expression such as
+// "role=builder,team!=android", "ci && !ephemeral" or "os in (macos)".
+func ParseSelector(expr string) (Selector, error) {
+	tokens, err := lexSelector(expr)
+	if err != nil {
+		return nil, fmt.Errorf("selector %q: %w", expr, err)
+	}
+	p := &selectorParser{tokens: tokens}
+	sel, err := p.parseOr()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected %q", p.tokens[p.pos])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("selector %q: %w", expr, err)
+	}
+	return sel, nil
+}
+
+// FilterBySelector returns only VMs matched by sel. A nil selector matches
+// every VM.
+func FilterBySelector(vms []ResolvedVM, sel Selector) []ResolvedVM {
+	if sel == nil {
+		return vms
+	}
+	var result []ResolvedVM
+	for _, vm := range vms {
+		if sel.Matches(vm) {
+			result = append(result, vm)
+		}
+	}
+	return result
+}
+
+// lookup returns the value of key for vm: a label, or the built-in name and
+// os keys.
+func (vm ResolvedVM) lookup(key string) (string, bool) {
+	if v, ok := vm.Labels[key]; ok {
+		return v, true
+	}
+	switch key {
+	case "name":
+		return vm.Name, true
+	case "os":
+		return vm.OS, true
+	}
+	return "", false
+}
+
+type (
+	selAnd    []Selector
+	selOr     []Selector
+	selNot    struct{ sel Selector }
+	selExists struct{ key string }
+	selIn     struct {
+		key    string
+		values []string
+		negate bool
+	}
+)
+
+func (s selAnd) Matches(vm ResolvedVM) bool {
+	for _, sel := range s {
+		if !sel.Matches(vm) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s selOr) Matches(vm ResolvedVM) bool {
+	for _, sel := range s {
+		if sel.Matches(vm) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s selNot) Matches(vm ResolvedVM) bool { return !s.sel.Matches(vm) }
+
+func (s selExists) Matches(vm ResolvedVM) bool {
+	if slices.Contains(vm.Tags, s.key) {
+		return true
+	}
+	_, ok := vm.Labels[s.key]
+	return ok
+}
+
+// Matches for "key != value" and "key notin (...)" is also true when the VM
+// has no such key, matching the usual label selector semantics.
+func (s selIn) Matches(vm ResolvedVM) bool {
+	value, ok := vm.lookup(s.key)
+	found := ok && slices.ContainsFunc(s.values, func(v string) bool {
+		return strings.EqualFold(v, value)
+	})
+	return found != s.negate
+}
+
+type selectorParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *selectorParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *selectorParser) next() string {
+	tok := p.peek()
+	p.pos++
+	return tok
+}
+
+func (p *selectorParser) expect(tok string) error {
+	if got := p.next(); got != tok {
+		if got == "" {
+			return fmt.Errorf("expected %q, got end of expression", tok)
+		}
+		return fmt.Errorf("expected %q, got %q", tok, got)
+	}
+	return nil
+}
+
+func (p *selectorParser) parseOr() (Selector, error) {
+	var or selOr
+	for {
+		sel, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		or = append(or, sel)
+		if p.peek() != "||" {
+			break
+		}
+		p.next()
+	}
+	if len(or) == 1 {
+		return or[0], nil
+	}
+	return or, nil
+}
+
+func (p *selectorParser) parseAnd() (Selector, error) {
+	var and selAnd
+	for {
+		sel, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		and = append(and, sel)
+		if tok := p.peek(); tok != "&&" && tok != "," {
+			break
+		}
+		p.next()
+	}
+	if len(and) == 1 {
+		return and[0], nil
+	}
+	return and, nil
+}
+
+func (p *selectorParser) parseUnary() (Selector, error) {
+	switch p.peek() {
+	case "!":
+		p.next()
+		sel, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return selNot{sel}, nil
+	case "(":
+		p.next()
+		sel, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return sel, p.expect(")")
+	}
+	return p.parseTerm()
+}
+
+func (p *selectorParser) parseTerm() (Selector, error) {
+	key := p.next()
+	if !isSelectorWord(key) {
+		if key == "" {
+			return nil, fmt.Errorf("unexpected end of expression")
+		}
+		return nil, fmt.Errorf("unexpected %q", key)
+	}
+
+	switch op := p.peek(); op {
+	case "=", "==", "!=":
+		p.next()
+		value := p.next()
+		if !isSelectorWord(value) {
+			return nil, fmt.Errorf("expected a value after %q", key+op)
+		}
+		return selIn{key: key, values: []string{value}, negate: op == "!="}, nil
+	case "in", "notin":
+		p.next()
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		var values []string
+		for {
+			value := p.next()
+			if !isSelectorWord(value) {
+				return nil, fmt.Errorf("expected a value in %s list for %q", op, key)
+			}
+			values = append(values, value)
+			if p.peek() != "," {
+				break
+			}
+			p.next()
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return selIn{key: key, values: values, negate: op == "notin"}, nil
+	}
+	return selExists{key: key}, nil
+}
+
+func isSelectorWord(tok string) bool {
+	return tok != "" && isSelectorWordRune(rune(tok[0]))
+}
+
+func isSelectorWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_-./", r)
+}
+
+// lexSelector splits a selector expression into words and operators.
+func lexSelector(expr string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(expr); {
+		r := rune(expr[i])
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case isSelectorWordRune(r):
+			j := i
+			for j < len(expr) && isSelectorWordRune(rune(expr[j])) {
+				j++
+			}
+			tokens = append(tokens, expr[i:j])
+			i = j
+		case strings.HasPrefix(expr[i:], "&&"), strings.HasPrefix(expr[i:], "||"),
+			strings.HasPrefix(expr[i:], "=="), strings.HasPrefix(expr[i:], "!="):
+			tokens = append(tokens, expr[i:i+2])
+			i += 2
+		case strings.ContainsRune("!=,()", r):
+			tokens = append(tokens, string(r))
+			i++
+		default:
+			return nil, fmt.Errorf("unexpected character %q", r)
+		}
+	}
+	return tokens, nil
+}
@@ -0,0 +1,73 @@
+package fleet
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSelectorMatches(t *testing.T) {
+	vms := []ResolvedVM{
+		{Name: "ios-builder", OS: "macos", Tags: []string{"ci"}, Labels: map[string]string{"team": "ios", "role": "builder"}},
+		{Name: "android-builder", OS: "linux", Tags: []string{"ci", "ephemeral"}, Labels: map[string]string{"team": "android", "role": "builder"}},
+		{Name: "dev-main", OS: "macos", Tags: []string{"dev"}},
+	}
+
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{"role=builder,team!=android", []string{"ios-builder"}},
+		{"ci && !ephemeral", []string{"ios-builder"}},
+		{"os in (macos)", []string{"ios-builder", "dev-main"}},
+		{"os notin (macos, windows)", []string{"android-builder"}},
+		{"team == ios || dev", []string{"ios-builder", "dev-main"}},
+		{"!(ci) , name=dev-main", []string{"dev-main"}},
+		{"team", []string{"ios-builder", "android-builder"}},
+	}
+	for _, tt := range tests {
+		sel, err := ParseSelector(tt.expr)
+		if err != nil {
+			t.Fatalf("ParseSelector(%q) returned error: %v", tt.expr, err)
+		}
+		var got []string
+		for _, vm := range FilterBySelector(vms, sel) {
+			got = append(got, vm.Name)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseSelector(%q) matched %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestParseSelectorErrors(t *testing.T) {
+	for _, expr := range []string{"", "role=", "os in macos", "(ci", "ci &&", "a = b c", "role~x"} {
+		if _, err := ParseSelector(expr); err == nil {
+			t.Errorf("ParseSelector(%q) returned no error", expr)
+		}
+	}
+}
+
+func TestFilterByNamesAndTags(t *testing.T) {
+	vms := []ResolvedVM{
+		{Name: "ci-runner-1", Tags: []string{"ci"}},
+		{Name: "ci-runner-2", Tags: []string{"ci"}},
+		{Name: "dev-main", Tags: []string{"dev"}},
+		{Name: "golden-image", Tags: []string{"template"}},
+	}
+
+	var got []string
+	for _, vm := range FilterByNames(vms, []string{"ci-runner-*", "dev-main"}) {
+		got = append(got, vm.Name)
+	}
+	if want := []string{"ci-runner-1", "ci-runner-2", "dev-main"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FilterByNames() = %v, want %v", got, want)
+	}
+
+	got = nil
+	for _, vm := range FilterByTags(vms, []string{"dev", "template"}) {
+		got = append(got, vm.Name)
+	}
+	if want := []string{"dev-main", "golden-image"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FilterByTags() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,84 @@
+package fleet
+
+import (
+	"fmt"
+
+	"github.com/hoalong/lume-fleet/lume"
+	"gopkg.in/yaml.v3"
+)
+
+// SizePreset is a named set of VM resources selected with `size:`.
+type SizePreset struct {
+	CPU      int    `yaml:"cpu,omitempty"`
+	Memory   string `yaml:"memory,omitempty"`
+	DiskSize string `yaml:"disk-size,omitempty"`
+
+	// at is where the preset starts in the YAML source.
+	at Position
+}
+
+// UnmarshalYAML decodes a size preset and records where it starts.
+func (p *SizePreset) UnmarshalYAML(node *yaml.Node) error {
+	type plain SizePreset
+	if err := node.Decode((*plain)(p)); err != nil {
+		return err
+	}
+	p.at = positionOf(node)
+	return nil
+}
+
+// presetKeys are the VM fields a size preset sets.
+var presetKeys = []string{"cpu", "memory", "disk-size"}
+
+// builtinSizes are the presets available without a sizes block.
+var builtinSizes = map[string]SizePreset{
+	"small":  {CPU: 2, Memory: "4GB", DiskSize: "30GB"},
+	"medium": {CPU: 4, Memory: "8GB", DiskSize: "50GB"},
+	"large":  {CPU: 8, Memory: "16GB", DiskSize: "100GB"},
+	"xl":     {CPU: 12, Memory: "32GB", DiskSize: "200GB"},
+}
+
+// SizePreset returns the named preset. Fields set in the sizes block
+// replace those of the built-in preset with the same name.
+func (c *FleetConfig) SizePreset(name string) (SizePreset, bool) {
+	preset, builtin := builtinSizes[name]
+	custom, ok := c.Sizes[name]
+	if !ok {
+		return preset, builtin
+	}
+	if custom.CPU != 0 {
+		preset.CPU = custom.CPU
+	}
+	if custom.Memory != "" {
+		preset.Memory = custom.Memory
+	}
+	if custom.DiskSize != "" {
+		preset.DiskSize = custom.DiskSize
+	}
+	return preset, true
+}
+
+// spec returns the preset as a VM spec layer.
+func (p SizePreset) spec() VMSpec {
+	return VMSpec{CPU: p.CPU, Memory: p.Memory, DiskSize: p.DiskSize}
+}
+
+// SizeDrift describes how the resources of an existing VM differ from the
+// resources its size preset (and any per-field overrides) resolve to. It
+// returns nil for VMs without a size.
+func SizeDrift(vm ResolvedVM, actual lume.VM) []string {
+	if vm.Size == "" {
+		return nil
+	}
+	var drift []string
+	if actual.CPUCount != vm.CPU {
+		drift = append(drift, fmt.Sprintf("cpu %d, want %d", actual.CPUCount, vm.CPU))
+	}
+	if want, err := ParseSize(vm.Memory); err == nil && actual.MemorySize != want*1024*1024 {
+		drift = append(drift, fmt.Sprintf("memory %s, want %s", FormatSize(actual.MemorySize/(1024*1024)), vm.Memory))
+	}
+	if want, err := ParseSize(vm.DiskSize); err == nil && actual.DiskSize != nil && actual.DiskSize.Total != want*1024*1024 {
+		drift = append(drift, fmt.Sprintf("disk-size %s, want %s", FormatSize(actual.DiskSize.Total/(1024*1024)), vm.DiskSize))
+	}
+	return drift
+}
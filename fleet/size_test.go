@@ -0,0 +1,101 @@
+package fleet
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hoalong/lume-fleet/lume"
+)
+
+func TestResolveExpandsSizePresets(t *testing.T) {
+	cfg := FleetConfig{
+		Defaults: VMDefaults{Size: "small", CPU: 3},
+		Sizes: map[string]SizePreset{
+			"large": {Memory: "24GB"},
+			"gpu":   {CPU: 10, Memory: "64GB", DiskSize: "500GB"},
+		},
+		Profiles: map[string]VMSpec{
+			"builder": {Size: "large"},
+		},
+		VMs: map[string]VMSpec{
+			"tiny":    {},
+			"builder": {Extends: []string{"builder"}, DiskSize: "150GB"},
+			"trainer": {Size: "gpu", CPU: 12},
+		},
+	}
+
+	resolved, err := cfg.Resolve()
+	if err != nil {
+		t.Fatalf("Resolve() returned error: %v", err)
+	}
+	got := map[string][4]string{}
+	for _, vm := range resolved {
+		got[vm.Name] = [4]string{vm.Size, strconv.Itoa(vm.CPU), vm.Memory, vm.DiskSize}
+	}
+	want := map[string][4]string{
+		"tiny":    {"small", "3", "4GB", "30GB"},
+		"builder": {"large", "8", "24GB", "150GB"},
+		"trainer": {"gpu", "12", "64GB", "500GB"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("resolved sizes = %v, want %v", got, want)
+	}
+}
+
+func TestResolveRejectsUnknownSize(t *testing.T) {
+	cfg := FleetConfig{VMs: map[string]VMSpec{"vm": {Size: "huge"}}}
+
+	_, err := cfg.Resolve()
+	if err == nil || !strings.Contains(err.Error(), `unknown size "huge"`) {
+		t.Fatalf("Resolve() error = %v, want unknown size", err)
+	}
+}
+
+func TestValidateChecksSizePresets(t *testing.T) {
+	path := writeConfig(t, `sizes:
+  odd:
+    cpu: 2
+    memory: 4XB
+vms:
+  dev:
+    size: small
+`)
+
+	problems, err := ValidateConfig(LoadOptions{Paths: []string{path}})
+	if err != nil {
+		t.Fatalf("ValidateConfig() returned error: %v", err)
+	}
+	want := []string{
+		path + `:3:5: size "odd": invalid disk-size ""`,
+		path + `:3:5: size "odd": invalid memory "4XB"`,
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("ValidateConfig() = %v, want invalid memory and disk-size", problems)
+	}
+	for i, w := range want {
+		if got := problems[i].String(); got != w {
+			t.Errorf("problem %d = %q, want %q", i, got, w)
+		}
+	}
+}
+
+func TestSizeDrift(t *testing.T) {
+	vm := ResolvedVM{Name: "dev", Size: "medium", CPU: 4, Memory: "8GB", DiskSize: "50GB"}
+	actual := lume.VM{
+		CPUCount:   6,
+		MemorySize: 8 * 1024 * 1024 * 1024,
+		DiskSize:   &lume.DiskSize{Total: 80 * 1024 * 1024 * 1024},
+	}
+
+	want := []string{"cpu 6, want 4", "disk-size 80GB, want 50GB"}
+	if got := SizeDrift(vm, actual); !reflect.DeepEqual(got, want) {
+		t.Fatalf("SizeDrift() = %v, want %v", got, want)
+	}
+
+	vm.Size = ""
+	if got := SizeDrift(vm, actual); got != nil {
+		t.Fatalf("SizeDrift() without size = %v, want nil", got)
+	}
+}
@@ -0,0 +1,37 @@
+package fleet
+
+import (
+	"maps"
+)
+
+// SSHSpec configures how lume-fleet connects to a VM over SSH. Blocks from
+// defaults, profiles and the VM are merged field by field.
+type SSHSpec struct {
+	User         string            `yaml:"user,omitempty" json:"user,omitempty"`
+	IdentityFile string            `yaml:"identity-file,omitempty" json:"identityFile,omitempty"`
+	Port         int               `yaml:"port,omitempty" json:"port,omitempty"`
+	Options      map[string]string `yaml:"options,omitempty" json:"options,omitempty"`
+}
+
+// merge returns s with the fields set in over applied on top. Options are
+// merged key by key.
+func (s SSHSpec) merge(over SSHSpec) SSHSpec {
+	if over.User != "" {
+		s.User = over.User
+	}
+	if over.IdentityFile != "" {
+		s.IdentityFile = over.IdentityFile
+	}
+	if over.Port != 0 {
+		s.Port = over.Port
+	}
+	if len(over.Options) > 0 {
+		options := maps.Clone(s.Options)
+		if options == nil {
+			options = map[string]string{}
+		}
+		maps.Copy(options, over.Options)
+		s.Options = options
+	}
+	return s
+}
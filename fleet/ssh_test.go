@@ -0,0 +1,51 @@
+package fleet
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestResolveMergesSSHBlocks(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+	cfg := FleetConfig{
+		Defaults: VMDefaults{SSH: &SSHSpec{
+			IdentityFile: "~/.ssh/fleet",
+			Options:      map[string]string{"StrictHostKeyChecking": "no"},
+		}},
+		Profiles: map[string]VMSpec{
+			"linux": {SSH: &SSHSpec{User: "ubuntu"}},
+		},
+		VMs: map[string]VMSpec{
+			"mac":   {},
+			"linux": {Extends: []string{"linux"}, SSH: &SSHSpec{Port: 2222, Options: map[string]string{"ConnectTimeout": "5"}}},
+		},
+	}
+
+	resolved, err := cfg.Resolve()
+	if err != nil {
+		t.Fatalf("Resolve() returned error: %v", err)
+	}
+	got := map[string]SSHSpec{}
+	for _, vm := range resolved {
+		got[vm.Name] = vm.SSH
+	}
+	key := filepath.Join(home, ".ssh/fleet")
+	want := map[string]SSHSpec{
+		"mac": {User: "lume", IdentityFile: key, Options: map[string]string{"StrictHostKeyChecking": "no"}},
+		"linux": {User: "ubuntu", IdentityFile: key, Port: 2222, Options: map[string]string{
+			"StrictHostKeyChecking": "no",
+			"ConnectTimeout":        "5",
+		}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("resolved ssh = %+v, want %+v", got, want)
+	}
+	if opts := cfg.Defaults.SSH.Options; len(opts) != 1 {
+		t.Fatalf("defaults ssh options were modified: %v", opts)
+	}
+}
@@ -0,0 +1,350 @@
+package fleet
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Position is a location in a config file. Line and Column are 1-based and
+// zero when unknown.
+type Position struct {
+	File   string
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	if p.Line == 0 {
+		return p.File
+	}
+	s := fmt.Sprintf("%d:%d", p.Line, p.Column)
+	if p.File != "" {
+		s = p.File + ":" + s
+	}
+	return s
+}
+
+func positionOf(node *yaml.Node) Position {
+	return Position{Line: node.Line, Column: node.Column}
+}
+
+// Problem is one issue found in a fleet config.
+type Problem struct {
+	Pos     Position
+	Message string
+}
+
+func (p Problem) String() string {
+	if pos := p.Pos.String(); pos != "" {
+		return pos + ": " + p.Message
+	}
+	return p.Message
+}
+
+// Problems is a list of config problems reported together. It implements
+// error so that callers that only care about success can treat it as one.
+type Problems []Problem
+
+func (ps Problems) Error() string {
+	lines := make([]string, len(ps))
+	for i, p := range ps {
+		lines[i] = p.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (ps Problems) sort() {
+	sort.SliceStable(ps, func(i, j int) bool {
+		a, b := ps[i].Pos, ps[j].Pos
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		if a.Column != b.Column {
+			return a.Column < b.Column
+		}
+		return ps[i].Message < ps[j].Message
+	})
+}
+
+var (
+	tagPattern  = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+	typeErrLine = regexp.MustCompile(`^line (\d+): (.*)$`)
+)
+
+// ValidateConfig loads the files selected by opts like Load and reports every
+// problem found: unknown keys, type errors, conflicting definitions, and
+// everything Validate checks. err is only set when a file cannot be read or
+// parsed at all.
+func ValidateConfig(opts LoadOptions) (Problems, error) {
+	cfg, problems, err := loadConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	problems = append(problems, cfg.Validate()...)
+	problems.sort()
+	return problems, nil
+}
+
+// Validate resolves every VM and checks the result more strictly than
+// Resolve: OS values, CPU counts, tag syntax, duplicate VNC ports, that
+// image files exist, that size presets are complete and the host block.
+func (c *FleetConfig) Validate() Problems {
+	var problems Problems
+
+	vncOwners := map[int]string{}
+	for _, name := range c.VMNames() {
+		vm, prov, errs := c.resolveVM(name, c.VMs[name])
+		problems = append(problems, errs...)
+		if len(prov.origins) == 0 {
+			// extends could not be expanded; nothing else to check.
+			continue
+		}
+		add := func(key, format string, args ...any) {
+			problems = append(problems, Problem{
+				Pos:     prov.position(key),
+				Message: fmt.Sprintf("VM %q: ", name) + fmt.Sprintf(format, args...) + fromOrigin(prov.origins, key),
+			})
+		}
+
+		if !strings.EqualFold(vm.OS, "macos") && !strings.EqualFold(vm.OS, "linux") {
+			add("os", "invalid os %q (must be macos or linux)", vm.OS)
+		}
+		if vm.CPU < 1 {
+			add("cpu", "invalid cpu %d (must be at least 1)", vm.CPU)
+		}
+		for _, tag := range vm.Tags {
+			if !tagPattern.MatchString(tag) {
+				add("tags", "invalid tag %q (use letters, digits, '.', '_' and '-')", tag)
+			}
+		}
+		for key, value := range vm.Labels {
+			if !tagPattern.MatchString(key) {
+				add("labels", "invalid label key %q", key)
+			}
+			if value != "" && !tagPattern.MatchString(value) {
+				add("labels", "invalid value %q for label %q", value, key)
+			}
+		}
+		if vm.VNCPort != 0 {
+			if owner, ok := vncOwners[vm.VNCPort]; ok {
+				add("vnc-port", "vnc-port %d is already used by VM %q", vm.VNCPort, owner)
+			} else {
+				vncOwners[vm.VNCPort] = name
+			}
+		}
+		if vm.SSH.IdentityFile != "" {
+			if _, err := os.Stat(vm.SSH.IdentityFile); err != nil {
+				add("ssh", "ssh identity-file %q not found", vm.SSH.IdentityFile)
+			}
+		}
+		if isImagePath(vm.Image) {
+			if _, err := os.Stat(vm.Image); err != nil {
+				add("image", "image %q not found", vm.Image)
+			}
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(c.Sizes)) {
+		preset, _ := c.SizePreset(name)
+		add := func(format string, args ...any) {
+			problems = append(problems, Problem{
+				Pos:     c.Sizes[name].at,
+				Message: fmt.Sprintf("size %q: ", name) + fmt.Sprintf(format, args...),
+			})
+		}
+		if preset.CPU < 1 {
+			add("invalid cpu %d (must be at least 1)", preset.CPU)
+		}
+		if _, err := ParseSize(preset.Memory); err != nil || preset.Memory == "" {
+			add("invalid memory %q", preset.Memory)
+		}
+		if _, err := ParseSize(preset.DiskSize); err != nil || preset.DiskSize == "" {
+			add("invalid disk-size %q", preset.DiskSize)
+		}
+	}
+
+	host := func(key, format string, args ...any) {
+		problems = append(problems, Problem{Pos: c.Host.present[key], Message: "host: " + fmt.Sprintf(format, args...)})
+	}
+	if _, err := ParseSize(c.Host.Memory); err != nil {
+		host("memory", "invalid memory: %v", err)
+	}
+	if c.Host.MacOSLimit < 0 {
+		host("macos-limit", "invalid macos-limit %d (must be at least 1)", c.Host.MacOSLimit)
+	}
+	if c.Host.Overcommit < 0 {
+		host("overcommit", "invalid overcommit %v (must be positive)", c.Host.Overcommit)
+	}
+
+	return problems
+}
+
+// isImagePath reports whether image refers to a local file rather than
+// "latest" or a download URL.
+func isImagePath(image string) bool {
+	if image == "" || strings.EqualFold(image, "latest") {
+		return false
+	}
+	if u, err := url.Parse(image); err == nil && u.Scheme != "" && u.Host != "" {
+		return false
+	}
+	return true
+}
+
+// checkKeys walks node against the yaml tags of t and reports keys that t
+// does not accept. where names the enclosing block for messages.
+func checkKeys(path string, node *yaml.Node, t reflect.Type, where string) Problems {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if node.Kind == yaml.AliasNode {
+		// Anchored content is checked where it is defined.
+		return nil
+	}
+
+	var problems Problems
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if key.Value == "<<" {
+				continue
+			}
+			field, ok := fields[key.Value]
+			if !ok {
+				pos := positionOf(key)
+				pos.File = path
+				msg := fmt.Sprintf("unknown key %q%s", key.Value, inBlock(where))
+				if s := suggest(key.Value, fields); s != "" {
+					msg += fmt.Sprintf(" (did you mean %q?)", s)
+				}
+				problems = append(problems, Problem{Pos: pos, Message: msg})
+				continue
+			}
+			problems = append(problems, checkKeys(path, value, field.Type, joinPath(where, key.Value))...)
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			problems = append(problems, checkKeys(path, node.Content[i+1], t.Elem(), joinPath(where, node.Content[i].Value))...)
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			return nil
+		}
+		for i, item := range node.Content {
+			problems = append(problems, checkKeys(path, item, t.Elem(), fmt.Sprintf("%s[%d]", where, i))...)
+		}
+	}
+	return problems
+}
+
+// yamlFields maps the yaml key of each exported field of t to the field.
+func yamlFields(t reflect.Type) map[string]reflect.StructField {
+	fields := map[string]reflect.StructField{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f
+	}
+	return fields
+}
+
+func joinPath(where, key string) string {
+	if where == "" {
+		return key
+	}
+	return where + "." + key
+}
+
+func inBlock(where string) string {
+	if where == "" {
+		return ""
+	}
+	return " in " + where
+}
+
+// suggest returns the known key closest to key, if it is close enough to be
+// a likely typo.
+func suggest(key string, fields map[string]reflect.StructField) string {
+	best, bestDist := "", 3
+	for name := range fields {
+		if d := editDistance(key, name); d < bestDist || (d == bestDist && best != "" && name < best) {
+			best, bestDist = name, d
+		}
+	}
+	return best
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// typeErrorProblems converts the "line N: ..." messages of a yaml.TypeError
+// into problems.
+func typeErrorProblems(path string, err *yaml.TypeError) Problems {
+	var problems Problems
+	for _, msg := range err.Errors {
+		pos := Position{File: path}
+		if m := typeErrLine.FindStringSubmatch(msg); m != nil {
+			pos.Line, _ = strconv.Atoi(m[1])
+			msg = m[2]
+		}
+		problems = append(problems, Problem{Pos: pos, Message: msg})
+	}
+	return problems
+}
+
+// asProblems returns err as Problems, wrapping other errors in one problem.
+func asProblems(err error) Problems {
+	var problems Problems
+	if errors.As(err, &problems) {
+		return problems
+	}
+	return Problems{{Message: err.Error()}}
+}
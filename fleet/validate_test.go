@@ -0,0 +1,68 @@
+package fleet
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "fleet.yml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	return path
+}
+
+func TestValidateConfigReportsAllProblemsWithPositions(t *testing.T) {
+	path := writeConfig(t, `defaults:
+  cpus: 4
+vms:
+  mac-1:
+    memory: 8XB
+    vnc-port: 5901
+  mac-2:
+    os: windows
+    vnc-port: 5901
+    tags: ["bad tag"]
+  linux-1:
+    os: linux
+    image: /does/not/exist.iso
+`)
+
+	problems, err := ValidateConfig(LoadOptions{Paths: []string{path}})
+	if err != nil {
+		t.Fatalf("ValidateConfig() returned error: %v", err)
+	}
+
+	want := []string{
+		path + `:2:3: unknown key "cpus" in defaults (did you mean "cpu"?)`,
+		path + `:5:13: VM "mac-1": invalid memory`,
+		path + `:8:9: VM "mac-2": invalid os "windows"`,
+		path + `:9:15: VM "mac-2": vnc-port 5901 is already used by VM "mac-1"`,
+		path + `:10:11: VM "mac-2": invalid tag "bad tag"`,
+		path + `:13:12: VM "linux-1": image "/does/not/exist.iso" not found`,
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("ValidateConfig() returned %d problems, want %d:\n%s", len(problems), len(want), problems)
+	}
+	for i, w := range want {
+		if !strings.HasPrefix(problems[i].String(), w) {
+			t.Errorf("problem %d = %q, want prefix %q", i, problems[i], w)
+		}
+	}
+}
+
+func TestLoadConfigRejectsUnknownKeys(t *testing.T) {
+	path := writeConfig(t, `vms:
+  dev:
+    cpus: 8
+`)
+
+	_, err := LoadConfig(path)
+	if err == nil || !strings.Contains(err.Error(), `unknown key "cpus" in vms.dev`) {
+		t.Fatalf("LoadConfig() error = %v, want unknown key cpus", err)
+	}
+}
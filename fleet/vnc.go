@@ -0,0 +1,35 @@
+package fleet
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// portInUse reports whether a TCP port is already bound on this host.
+func portInUse(port int) bool {
+	ln, err := net.Listen("tcp", ":"+strconv.Itoa(port))
+	if err != nil {
+		return true
+	}
+	ln.Close()
+	return false
+}
+
+// CheckVNCPort reports a conflict for the fixed VNC port of vm, which is
+// about to be created: another VM of the fleet configured with the same
+// port, or the port already being bound on this host.
+func CheckVNCPort(vm ResolvedVM, fleetVMs []ResolvedVM) error {
+	if vm.VNCPort == 0 {
+		return nil
+	}
+	for _, other := range fleetVMs {
+		if other.Name != vm.Name && other.VNCPort == vm.VNCPort {
+			return fmt.Errorf("vnc-port %d is also configured for VM %q", vm.VNCPort, other.Name)
+		}
+	}
+	if portInUse(vm.VNCPort) {
+		return fmt.Errorf("vnc-port %d is already in use on this host", vm.VNCPort)
+	}
+	return nil
+}
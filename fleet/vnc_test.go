@@ -0,0 +1,43 @@
+package fleet
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestCheckVNCPort(t *testing.T) {
+	ln, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	bound := ln.Addr().(*net.TCPAddr).Port
+
+	fleetVMs := []ResolvedVM{
+		{Name: "dev", VNCPort: 5901},
+		{Name: "other", VNCPort: 5901},
+		{Name: "bound", VNCPort: bound},
+		{Name: "auto"},
+	}
+	tests := []struct {
+		vm   ResolvedVM
+		want string
+	}{
+		{fleetVMs[0], `also configured for VM "other"`},
+		{fleetVMs[2], "already in use on this host"},
+		{fleetVMs[3], ""},
+	}
+	for _, tt := range tests {
+		err := CheckVNCPort(tt.vm, fleetVMs)
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("CheckVNCPort(%s) returned error: %v", tt.vm.Name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("CheckVNCPort(%s) error = %v, want %q", tt.vm.Name, err, tt.want)
+		}
+	}
+}
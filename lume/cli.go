@@ -83,8 +83,8 @@ func buildCreateCommandArgs(req CreateRequest) []string {
 }
 
 // RunVMViaCLI shells out to `lume run <name> --no-display` with optional flags.
-func RunVMViaCLI(name, sharedDir, mountISO string) error {
-	args := buildRunCommandArgs(name, sharedDir, mountISO)
+func RunVMViaCLI(name string, sharedDirs []SharedDirectory, mountISO string) error {
+	args := buildRunCommandArgs(name, sharedDirs, mountISO)
 	cmd := exec.Command("lume", args...)
 
 	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
@@ -116,10 +116,10 @@ func RunVMViaCLI(name, sharedDir, mountISO string) error {
 	}
 }
 
-func buildRunCommandArgs(name, sharedDir, mountISO string) []string {
+func buildRunCommandArgs(name string, sharedDirs []SharedDirectory, mountISO string) []string {
 	args := []string{"run", name, "--no-display"}
-	if sharedDir != "" {
-		args = append(args, "--shared-dir", sharedDir)
+	for _, dir := range sharedDirs {
+		args = append(args, "--shared-dir", sharedDirArg(dir))
 	}
 	if mountISO != "" {
 		args = append(args, "--mount", mountISO)
@@ -127,6 +127,15 @@ func buildRunCommandArgs(name, sharedDir, mountISO string) []string {
 	return args
 }
 
+// sharedDirArg renders a shared directory as `path[:ro]`, the form `lume run
+// --shared-dir` accepts. Directories are read-write by default.
+func sharedDirArg(dir SharedDirectory) string {
+	if dir.ReadOnly {
+		return dir.HostPath + ":ro"
+	}
+	return dir.HostPath
+}
+
 // StopVMViaCLI shells out to `lume stop <name>`.
 func StopVMViaCLI(name string) error {
 	cmd := exec.Command("lume", "stop", name)
@@ -7,7 +7,7 @@ import (
 )
 
 func TestBuildRunCommandArgsWithMount(t *testing.T) {
-	args := buildRunCommandArgs("test-linux", "~/Projects", "/tmp/ubuntu.iso")
+	args := buildRunCommandArgs("test-linux", []SharedDirectory{{HostPath: "~/Projects"}}, "/tmp/ubuntu.iso")
 
 	want := []string{"run", "test-linux", "--no-display", "--shared-dir", "~/Projects", "--mount", "/tmp/ubuntu.iso"}
 	if !reflect.DeepEqual(args, want) {
@@ -16,7 +16,7 @@ func TestBuildRunCommandArgsWithMount(t *testing.T) {
 }
 
 func TestBuildRunCommandArgsWithoutOptionalFlags(t *testing.T) {
-	args := buildRunCommandArgs("test-linux", "", "")
+	args := buildRunCommandArgs("test-linux", nil, "")
 
 	want := []string{"run", "test-linux", "--no-display"}
 	if !reflect.DeepEqual(args, want) {
@@ -24,6 +24,23 @@ func TestBuildRunCommandArgsWithoutOptionalFlags(t *testing.T) {
 	}
 }
 
+func TestBuildRunCommandArgsWithMultipleSharedDirs(t *testing.T) {
+	dirs := []SharedDirectory{
+		{HostPath: "/Users/me/Projects"},
+		{HostPath: "/Users/me/Datasets", ReadOnly: true},
+	}
+	args := buildRunCommandArgs("dev", dirs, "")
+
+	want := []string{
+		"run", "dev", "--no-display",
+		"--shared-dir", "/Users/me/Projects",
+		"--shared-dir", "/Users/me/Datasets:ro",
+	}
+	if !reflect.DeepEqual(args, want) {
+		t.Fatalf("buildRunCommandArgs() = %v, want %v", args, want)
+	}
+}
+
 func TestBuildCreateCommandArgsForMacOS(t *testing.T) {
 	req := CreateRequest{
 		Name:       "mac-vm",
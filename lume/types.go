@@ -39,6 +39,12 @@ type CreateRequest struct {
 
 // RunRequest is the POST /lume/vms/{name}/run body.
 type RunRequest struct {
-	NoDisplay bool   `json:"noDisplay"`
-	SharedDir string `json:"sharedDir,omitempty"`
+	NoDisplay         bool              `json:"noDisplay"`
+	SharedDirectories []SharedDirectory `json:"sharedDirectories,omitempty"`
+}
+
+// SharedDirectory is a host directory exposed to a running VM.
+type SharedDirectory struct {
+	HostPath string `json:"hostPath"`
+	ReadOnly bool   `json:"readOnly"`
 }
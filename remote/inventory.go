@@ -0,0 +1,238 @@
+package remote
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Host is one VM in an inventory. Target.Host is empty for VMs without an
+// IP address; they are left out of Ansible inventories and commented out in
+// SSH config.
+type Host struct {
+	Target
+	Groups []string
+}
+
+// GroupName turns a tag or OS into a name Ansible accepts as a group.
+func GroupName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// SSHConfig renders hosts as ssh_config(5) Host entries.
+func SSHConfig(hosts []Host) []byte {
+	var b bytes.Buffer
+	for _, h := range hosts {
+		if h.Host == "" {
+			fmt.Fprintf(&b, "# %s: no IP address\n\n", h.Name)
+			continue
+		}
+		fmt.Fprintf(&b, "Host %s\n", h.Name)
+		fmt.Fprintf(&b, "  HostName %s\n", h.Host)
+		if h.User != "" {
+			fmt.Fprintf(&b, "  User %s\n", h.User)
+		}
+		if h.Port != 0 {
+			fmt.Fprintf(&b, "  Port %d\n", h.Port)
+		}
+		if h.IdentityFile != "" {
+			fmt.Fprintf(&b, "  IdentityFile %s\n", h.IdentityFile)
+		}
+		for _, key := range slices.Sorted(maps.Keys(h.Options)) {
+			fmt.Fprintf(&b, "  %s %s\n", key, h.Options[key])
+		}
+		b.WriteString("\n")
+	}
+	return b.Bytes()
+}
+
+// SSHConfigAddresses returns the HostName of each Host entry in an
+// ssh_config file written by SSHConfig.
+func SSHConfigAddresses(data []byte) map[string]string {
+	addrs := map[string]string{}
+	host := ""
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		switch fields[0] {
+		case "Host":
+			host = fields[1]
+		case "HostName":
+			if host != "" {
+				addrs[host] = fields[1]
+			}
+		}
+	}
+	return addrs
+}
+
+// hostVars returns the Ansible connection variables of h.
+func hostVars(h Host) map[string]any {
+	vars := map[string]any{"ansible_host": h.Host}
+	if h.User != "" {
+		vars["ansible_user"] = h.User
+	}
+	if h.Port != 0 {
+		vars["ansible_port"] = h.Port
+	}
+	if h.IdentityFile != "" {
+		vars["ansible_ssh_private_key_file"] = h.IdentityFile
+	}
+	if len(h.Options) > 0 {
+		var opts []string
+		for _, key := range slices.Sorted(maps.Keys(h.Options)) {
+			opts = append(opts, "-o "+key+"="+h.Options[key])
+		}
+		vars["ansible_ssh_common_args"] = strings.Join(opts, " ")
+	}
+	return vars
+}
+
+// reachable returns the hosts that have an IP address.
+func reachable(hosts []Host) []Host {
+	return slices.DeleteFunc(slices.Clone(hosts), func(h Host) bool { return h.Host == "" })
+}
+
+// groups maps each group name to its hosts, in host order.
+func groups(hosts []Host) map[string][]string {
+	out := map[string][]string{}
+	for _, h := range hosts {
+		for _, g := range h.Groups {
+			g = GroupName(g)
+			if !slices.Contains(out[g], h.Name) {
+				out[g] = append(out[g], h.Name)
+			}
+		}
+	}
+	return out
+}
+
+// AnsibleINI renders hosts as an Ansible INI inventory.
+func AnsibleINI(hosts []Host) []byte {
+	hosts = reachable(hosts)
+	var b bytes.Buffer
+	for _, h := range hosts {
+		vars := hostVars(h)
+		b.WriteString(h.Name)
+		for _, key := range slices.Sorted(maps.Keys(vars)) {
+			fmt.Fprintf(&b, " %s=%s", key, iniValue(vars[key]))
+		}
+		b.WriteString("\n")
+	}
+	byGroup := groups(hosts)
+	for _, g := range slices.Sorted(maps.Keys(byGroup)) {
+		fmt.Fprintf(&b, "\n[%s]\n", g)
+		for _, name := range byGroup[g] {
+			b.WriteString(name + "\n")
+		}
+	}
+	return b.Bytes()
+}
+
+// iniValue quotes values that contain spaces.
+func iniValue(v any) string {
+	s := fmt.Sprint(v)
+	if strings.ContainsAny(s, " \t\"") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// AnsibleYAML renders hosts as an Ansible YAML inventory.
+func AnsibleYAML(hosts []Host) ([]byte, error) {
+	hosts = reachable(hosts)
+	all := map[string]any{}
+	vars := map[string]any{}
+	for _, h := range hosts {
+		vars[h.Name] = hostVars(h)
+	}
+	if len(vars) > 0 {
+		all["hosts"] = vars
+	}
+	children := map[string]any{}
+	for g, names := range groups(hosts) {
+		members := map[string]any{}
+		for _, name := range names {
+			members[name] = nil
+		}
+		children[g] = map[string]any{"hosts": members}
+	}
+	if len(children) > 0 {
+		all["children"] = children
+	}
+
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(map[string]any{"all": all}); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), enc.Close()
+}
+
+// AnsibleJSON renders hosts in the format Ansible expects from a dynamic
+// inventory script called with --list.
+func AnsibleJSON(hosts []Host) ([]byte, error) {
+	hosts = reachable(hosts)
+	hostvars := map[string]any{}
+	var ungrouped []string
+	for _, h := range hosts {
+		hostvars[h.Name] = hostVars(h)
+		if len(h.Groups) == 0 {
+			ungrouped = append(ungrouped, h.Name)
+		}
+	}
+	byGroup := groups(hosts)
+	inv := map[string]any{
+		"_meta": map[string]any{"hostvars": hostvars},
+		"all":   map[string]any{"children": append([]string{"ungrouped"}, slices.Sorted(maps.Keys(byGroup))...)},
+		"ungrouped": map[string]any{
+			"hosts": nonNil(ungrouped),
+		},
+	}
+	for g, names := range byGroup {
+		inv[g] = map[string]any{"hosts": names}
+	}
+	data, err := json.MarshalIndent(inv, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// HostVarsJSON renders the variables of the named host, as Ansible expects
+// from a dynamic inventory script called with --host.
+func HostVarsJSON(hosts []Host, name string) ([]byte, error) {
+	vars := map[string]any{}
+	for _, h := range reachable(hosts) {
+		if h.Name == name {
+			vars = hostVars(h)
+		}
+	}
+	data, err := json.MarshalIndent(vars, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
@@ -0,0 +1,120 @@
+package remote
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testHosts = []Host{
+	{
+		Target: Target{Name: "dev-main", Host: "192.168.64.5", User: "lume", Options: map[string]string{"StrictHostKeyChecking": "no"}},
+		Groups: []string{"dev", "macos"},
+	},
+	{
+		Target: Target{Name: "ci-runner-1", Host: "192.168.64.6", User: "ubuntu", Port: 2222, IdentityFile: "/keys/ci"},
+		Groups: []string{"ci", "ephemeral.pool", "linux"},
+	},
+	{
+		Target: Target{Name: "ci-runner-2", User: "ubuntu"},
+		Groups: []string{"ci", "linux"},
+	},
+}
+
+func TestSSHConfig(t *testing.T) {
+	want := `Host dev-main
+  HostName 192.168.64.5
+  User lume
+  StrictHostKeyChecking no
+
+Host ci-runner-1
+  HostName 192.168.64.6
+  User ubuntu
+  Port 2222
+  IdentityFile /keys/ci
+
+# ci-runner-2: no IP address
+
+`
+	got := SSHConfig(testHosts)
+	if string(got) != want {
+		t.Fatalf("SSHConfig() =\n%s\nwant\n%s", got, want)
+	}
+
+	addrs := SSHConfigAddresses(got)
+	if want := map[string]string{"dev-main": "192.168.64.5", "ci-runner-1": "192.168.64.6"}; !reflect.DeepEqual(addrs, want) {
+		t.Fatalf("SSHConfigAddresses() = %v, want %v", addrs, want)
+	}
+}
+
+func TestAnsibleINI(t *testing.T) {
+	want := `dev-main ansible_host=192.168.64.5 ansible_ssh_common_args="-o StrictHostKeyChecking=no" ansible_user=lume
+ci-runner-1 ansible_host=192.168.64.6 ansible_port=2222 ansible_ssh_private_key_file=/keys/ci ansible_user=ubuntu
+
+[ci]
+ci-runner-1
+
+[dev]
+dev-main
+
+[ephemeral_pool]
+ci-runner-1
+
+[linux]
+ci-runner-1
+
+[macos]
+dev-main
+`
+	if got := string(AnsibleINI(testHosts)); got != want {
+		t.Fatalf("AnsibleINI() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestAnsibleYAML(t *testing.T) {
+	got, err := AnsibleYAML(testHosts)
+	if err != nil {
+		t.Fatalf("AnsibleYAML() returned error: %v", err)
+	}
+	for _, want := range []string{
+		"all:\n  children:\n    ci:\n      hosts:\n        ci-runner-1: null\n",
+		"  hosts:\n    ci-runner-1:\n      ansible_host: 192.168.64.6\n      ansible_port: 2222\n",
+	} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("AnsibleYAML() =\n%s\nwant it to contain\n%s", got, want)
+		}
+	}
+	if strings.Contains(string(got), "ci-runner-2") {
+		t.Errorf("AnsibleYAML() includes a host without IP address:\n%s", got)
+	}
+}
+
+func TestAnsibleJSON(t *testing.T) {
+	data, err := AnsibleJSON(testHosts)
+	if err != nil {
+		t.Fatalf("AnsibleJSON() returned error: %v", err)
+	}
+	var inv map[string]struct {
+		Hosts    []string                  `json:"hosts"`
+		Children []string                  `json:"children"`
+		HostVars map[string]map[string]any `json:"hostvars"`
+	}
+	if err := json.Unmarshal(data, &inv); err != nil {
+		t.Fatalf("AnsibleJSON() is not valid JSON: %v", err)
+	}
+	if got := inv["ci"].Hosts; !reflect.DeepEqual(got, []string{"ci-runner-1"}) {
+		t.Errorf("ci hosts = %v, want [ci-runner-1]", got)
+	}
+	if got := inv["all"].Children; !reflect.DeepEqual(got, []string{"ungrouped", "ci", "dev", "ephemeral_pool", "linux", "macos"}) {
+		t.Errorf("all children = %v", got)
+	}
+	if got := inv["_meta"].HostVars["dev-main"]["ansible_host"]; got != "192.168.64.5" {
+		t.Errorf("dev-main ansible_host = %v, want 192.168.64.5", got)
+	}
+
+	vars, err := HostVarsJSON(testHosts, "ci-runner-1")
+	if err != nil || !strings.Contains(string(vars), `"ansible_port": 2222`) {
+		t.Errorf("HostVarsJSON() = %s, %v", vars, err)
+	}
+}
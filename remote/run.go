@@ -0,0 +1,102 @@
+package remote
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"maps"
+	"os/exec"
+	"sync"
+)
+
+// sshCommand builds the ssh process for args. Tests replace it.
+var sshCommand = func(args ...string) *exec.Cmd {
+	return exec.Command("ssh", args...)
+}
+
+// Result is the outcome of running a command on one target.
+type Result struct {
+	Name string
+	// ExitCode is the remote command's exit status, or 255 when ssh itself
+	// failed to connect (as reported by ssh), or -1 when ssh did not run.
+	ExitCode int
+	// Stdout and Stderr hold the output when it was collected.
+	Stdout []byte
+	Stderr []byte
+	Err    error
+}
+
+// Run runs command on t without a terminal and returns its exit code.
+// BatchMode is enabled unless the target sets it, so that ssh fails instead
+// of prompting for a password.
+func Run(t Target, command []string, stdout, stderr io.Writer) (int, error) {
+	if _, ok := t.Options["BatchMode"]; !ok {
+		t.Options = withOption(t.Options, "BatchMode", "yes")
+	}
+	cmd := sshCommand(t.SSHArgs(command...)...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return 0, nil
+	case errors.As(err, &exitErr):
+		return exitErr.ExitCode(), nil
+	default:
+		return -1, err
+	}
+}
+
+// withOption returns a copy of options with key set.
+func withOption(options map[string]string, key, value string) map[string]string {
+	out := maps.Clone(options)
+	if out == nil {
+		out = map[string]string{}
+	}
+	out[key] = value
+	return out
+}
+
+// RunAll runs command on every target, at most parallel at a time, and
+// returns the results in target order. When output is nil the output of
+// each target is collected into its Result; otherwise output returns the
+// writers for a target, which are flushed afterwards if they have a Flush
+// method.
+func RunAll(targets []Target, command []string, parallel int, output func(Target) (stdout, stderr io.Writer)) []Result {
+	if parallel < 1 {
+		parallel = 1
+	}
+	results := make([]Result, len(targets))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			var stdout, stderr io.Writer
+			var outBuf, errBuf bytes.Buffer
+			if output != nil {
+				stdout, stderr = output(t)
+			} else {
+				stdout, stderr = &outBuf, &errBuf
+			}
+			code, err := Run(t, command, stdout, stderr)
+			for _, w := range []io.Writer{stdout, stderr} {
+				if f, ok := w.(interface{ Flush() error }); ok {
+					f.Flush()
+				}
+			}
+			results[i] = Result{Name: t.Name, ExitCode: code, Err: err}
+			if output == nil {
+				results[i].Stdout = outBuf.Bytes()
+				results[i].Stderr = errBuf.Bytes()
+			}
+		}()
+	}
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,85 @@
+package remote
+
+import (
+	"bytes"
+	"io"
+	"os/exec"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeSSH runs the remote command locally with sh, ignoring ssh options.
+func fakeSSH(t *testing.T) {
+	t.Helper()
+	orig := sshCommand
+	sshCommand = func(args ...string) *exec.Cmd {
+		return exec.Command("sh", "-c", args[len(args)-1])
+	}
+	t.Cleanup(func() { sshCommand = orig })
+}
+
+func TestRunAllCollectsOutputAndExitCodes(t *testing.T) {
+	fakeSSH(t)
+	targets := []Target{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+
+	results := RunAll(targets, []string{"echo out; echo err >&2; exit 3"}, 2, nil)
+
+	if len(results) != 3 {
+		t.Fatalf("RunAll() returned %d results, want 3", len(results))
+	}
+	for i, r := range results {
+		if r.Name != targets[i].Name || r.ExitCode != 3 || r.Err != nil {
+			t.Errorf("result %d = %+v, want %s with exit 3", i, r, targets[i].Name)
+		}
+		if string(r.Stdout) != "out\n" || string(r.Stderr) != "err\n" {
+			t.Errorf("result %d output = %q, %q", i, r.Stdout, r.Stderr)
+		}
+	}
+}
+
+func TestRunAllStreamsPrefixedLines(t *testing.T) {
+	fakeSSH(t)
+	var out bytes.Buffer
+	var mu sync.Mutex
+	output := func(t Target) (io.Writer, io.Writer) {
+		w := NewPrefixWriter(&out, t.Name+" | ", &mu)
+		return w, w
+	}
+
+	results := RunAll([]Target{{Name: "a"}, {Name: "b"}}, []string{"printf 'one\\ntwo'"}, 4, output)
+
+	for _, r := range results {
+		if r.ExitCode != 0 || r.Stdout != nil {
+			t.Errorf("result = %+v, want exit 0 without collected output", r)
+		}
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	var a []string
+	for _, l := range lines {
+		if strings.HasPrefix(l, "a | ") {
+			a = append(a, l)
+		}
+	}
+	if len(lines) != 4 || !reflect.DeepEqual(a, []string{"a | one", "a | two"}) {
+		t.Fatalf("output = %q, want two prefixed lines per target", out.String())
+	}
+}
+
+func TestRunEnablesBatchMode(t *testing.T) {
+	var got []string
+	orig := sshCommand
+	sshCommand = func(args ...string) *exec.Cmd {
+		got = args
+		return exec.Command("true")
+	}
+	t.Cleanup(func() { sshCommand = orig })
+
+	if _, err := Run(Target{Host: "h"}, []string{"true"}, io.Discard, io.Discard); err != nil {
+		t.Fatalf("Run() returned error: %v", err)
+	}
+	if want := []string{"-o", "BatchMode=yes", "h", "true"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("ssh args = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,61 @@
+package remote
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// scpCommand builds the scp process for args. Tests replace it.
+var scpCommand = func(args ...string) *exec.Cmd {
+	return exec.Command("scp", args...)
+}
+
+// Path returns the scp operand for path on the target, user@host:path.
+// IPv6 addresses are bracketed.
+func (t Target) Path(path string) string {
+	host := t.Host
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if t.User != "" {
+		host = t.User + "@" + host
+	}
+	return host + ":" + path
+}
+
+// SCPArgs returns the scp arguments that copy sources to dest. Either the
+// sources or dest are remote operands built with Path.
+func (t Target) SCPArgs(recursive bool, sources []string, dest string) []string {
+	args := t.options("-P")
+	if recursive {
+		args = append(args, "-r")
+	}
+	args = append(args, sources...)
+	return append(args, dest)
+}
+
+// Upload copies local sources to path on the target.
+func Upload(t Target, recursive bool, sources []string, path string, stdout, stderr io.Writer) error {
+	return runSCP(t, t.SCPArgs(recursive, sources, t.Path(path)), stdout, stderr)
+}
+
+// Download copies remote paths from the target to the local dest.
+func Download(t Target, recursive bool, paths []string, dest string, stdout, stderr io.Writer) error {
+	sources := make([]string, len(paths))
+	for i, p := range paths {
+		sources[i] = t.Path(p)
+	}
+	return runSCP(t, t.SCPArgs(recursive, sources, dest), stdout, stderr)
+}
+
+func runSCP(t Target, args []string, stdout, stderr io.Writer) error {
+	cmd := scpCommand(args...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: scp: %w", t.Name, err)
+	}
+	return nil
+}
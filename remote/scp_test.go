@@ -0,0 +1,49 @@
+package remote
+
+import (
+	"io"
+	"os/exec"
+	"reflect"
+	"testing"
+)
+
+func TestTargetPath(t *testing.T) {
+	tests := []struct {
+		target Target
+		want   string
+	}{
+		{Target{Host: "192.168.64.5", User: "lume"}, "lume@192.168.64.5:/tmp/x"},
+		{Target{Host: "fe80::1"}, "[fe80::1]:/tmp/x"},
+	}
+	for _, tt := range tests {
+		if got := tt.target.Path("/tmp/x"); got != tt.want {
+			t.Errorf("Path() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestUploadAndDownloadArgs(t *testing.T) {
+	var got [][]string
+	orig := scpCommand
+	scpCommand = func(args ...string) *exec.Cmd {
+		got = append(got, args)
+		return exec.Command("true")
+	}
+	t.Cleanup(func() { scpCommand = orig })
+
+	target := Target{Name: "dev", Host: "10.0.0.2", User: "lume", Port: 2222, IdentityFile: "/k"}
+	if err := Upload(target, true, []string{"a.txt", "dir"}, "/tmp/", io.Discard, io.Discard); err != nil {
+		t.Fatalf("Upload() returned error: %v", err)
+	}
+	if err := Download(target, false, []string{"/var/log/x.log"}, "logs/dev", io.Discard, io.Discard); err != nil {
+		t.Fatalf("Download() returned error: %v", err)
+	}
+
+	want := [][]string{
+		{"-P", "2222", "-i", "/k", "-r", "a.txt", "dir", "lume@10.0.0.2:/tmp/"},
+		{"-P", "2222", "-i", "/k", "lume@10.0.0.2:/var/log/x.log", "logs/dev"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("scp args = %v, want %v", got, want)
+	}
+}
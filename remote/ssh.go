@@ -0,0 +1,92 @@
+// Package remote connects to fleet VMs with the system ssh and scp.
+package remote
+
+import (
+	"fmt"
+	"maps"
+	"net"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Target is an SSH endpoint for one VM.
+type Target struct {
+	Name         string
+	Host         string
+	User         string
+	Port         int
+	IdentityFile string
+	Options      map[string]string
+}
+
+// Destination returns the ssh destination, user@host or host.
+func (t Target) Destination() string {
+	if t.User == "" {
+		return t.Host
+	}
+	return t.User + "@" + t.Host
+}
+
+// address returns host:port for dialing.
+func (t Target) address() string {
+	port := t.Port
+	if port == 0 {
+		port = 22
+	}
+	return net.JoinHostPort(t.Host, strconv.Itoa(port))
+}
+
+// options returns the connection flags shared by ssh and scp. portFlag is
+// -p for ssh and -P for scp.
+func (t Target) options(portFlag string) []string {
+	var args []string
+	if t.Port != 0 {
+		args = append(args, portFlag, strconv.Itoa(t.Port))
+	}
+	if t.IdentityFile != "" {
+		args = append(args, "-i", t.IdentityFile)
+	}
+	for _, key := range slices.Sorted(maps.Keys(t.Options)) {
+		args = append(args, "-o", key+"="+t.Options[key])
+	}
+	return args
+}
+
+// SSHArgs returns the ssh arguments that run command on the target, or open
+// an interactive shell when command is empty.
+func (t Target) SSHArgs(command ...string) []string {
+	args := t.options("-p")
+	args = append(args, t.Destination())
+	return append(args, command...)
+}
+
+// Exec replaces the current process with ssh to the target.
+func Exec(t Target, command ...string) error {
+	path, err := exec.LookPath("ssh")
+	if err != nil {
+		return fmt.Errorf("ssh not found: %w", err)
+	}
+	argv := append([]string{"ssh"}, t.SSHArgs(command...)...)
+	return syscall.Exec(path, argv, os.Environ())
+}
+
+// WaitReachable waits until the target's SSH port accepts connections or
+// timeout passes.
+func WaitReachable(t Target, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		conn, err := net.DialTimeout("tcp", t.address(), 2*time.Second)
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%s: ssh port not reachable after %s: %w", t.Name, timeout, err)
+		}
+		time.Sleep(time.Second)
+	}
+}
@@ -0,0 +1,50 @@
+package remote
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSSHArgs(t *testing.T) {
+	target := Target{
+		Host:         "192.168.64.5",
+		User:         "admin",
+		Port:         2222,
+		IdentityFile: "/keys/fleet",
+		Options:      map[string]string{"StrictHostKeyChecking": "no", "ConnectTimeout": "5"},
+	}
+
+	got := target.SSHArgs("uname", "-a")
+	want := []string{
+		"-p", "2222", "-i", "/keys/fleet",
+		"-o", "ConnectTimeout=5", "-o", "StrictHostKeyChecking=no",
+		"admin@192.168.64.5", "uname", "-a",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("SSHArgs() = %v, want %v", got, want)
+	}
+
+	if got := (Target{Host: "10.0.0.2"}).SSHArgs(); !reflect.DeepEqual(got, []string{"10.0.0.2"}) {
+		t.Fatalf("SSHArgs() without options = %v, want [10.0.0.2]", got)
+	}
+}
+
+func TestWaitReachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	if err := WaitReachable(Target{Host: "127.0.0.1", Port: port}, time.Second); err != nil {
+		t.Fatalf("WaitReachable() returned error: %v", err)
+	}
+
+	ln.Close()
+	err = WaitReachable(Target{Name: "vm", Host: "127.0.0.1", Port: port}, 0)
+	if err == nil {
+		t.Fatalf("WaitReachable() on closed port %d returned no error", port)
+	}
+}
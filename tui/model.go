@@ -0,0 +1,257 @@
+// Package tui implements the interactive dashboard of `lume-fleet tui`.
+package tui
+
+import (
+	"os/exec"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/hoalong/lume-fleet/fleet"
+	"github.com/hoalong/lume-fleet/lume"
+	"github.com/hoalong/lume-fleet/ui"
+)
+
+// logLimit is how many log entries are kept per VM.
+const logLimit = 100
+
+// Actions are the operations the dashboard runs on the selected VM. They
+// are called outside of the UI goroutine and must not write to the
+// terminal.
+type Actions struct {
+	List    func() ([]lume.VM, error)
+	Start   func(vm fleet.ResolvedVM) error
+	Stop    func(vm fleet.ResolvedVM) error
+	Destroy func(vm fleet.ResolvedVM) error
+	// SSH returns the command that opens an interactive session to vm.
+	SSH func(vm fleet.ResolvedVM) (*exec.Cmd, error)
+}
+
+// LogEntry is one line of the operations log of a VM.
+type LogEntry struct {
+	Time time.Time
+	Text string
+	Err  bool
+}
+
+// Model is the bubbletea model of the dashboard.
+type Model struct {
+	vms      []fleet.ResolvedVM
+	actions  Actions
+	interval time.Duration
+
+	actual    []lume.VM
+	rows      []ui.StatusRow
+	refreshed time.Time
+	listErr   error
+
+	cursor int
+	// confirm is set while waiting for y to destroy the selected VM.
+	confirm bool
+	// busy holds the operation running on each VM.
+	busy map[string]string
+	logs map[string][]LogEntry
+
+	width, height int
+}
+
+// New returns a dashboard for vms that polls lume every interval.
+func New(vms []fleet.ResolvedVM, actions Actions, interval time.Duration) Model {
+	return Model{
+		vms:      vms,
+		actions:  actions,
+		interval: interval,
+		rows:     ui.BuildStatusRows(vms, nil),
+		busy:     map[string]string{},
+		logs:     map[string][]LogEntry{},
+	}
+}
+
+type (
+	tickMsg    struct{}
+	refreshMsg struct {
+		actual []lume.VM
+		err    error
+		at     time.Time
+	}
+	// opDoneMsg reports the end of an operation on a VM; done is logged
+	// when it succeeded.
+	opDoneMsg struct {
+		name, op, done string
+		err            error
+	}
+	sshReadyMsg struct {
+		name string
+		cmd  *exec.Cmd
+		err  error
+	}
+)
+
+func (m Model) Init() tea.Cmd {
+	return tea.Batch(m.refresh(), m.tick())
+}
+
+func (m Model) refresh() tea.Cmd {
+	list := m.actions.List
+	return func() tea.Msg {
+		actual, err := list()
+		return refreshMsg{actual: actual, err: err, at: time.Now()}
+	}
+}
+
+func (m Model) tick() tea.Cmd {
+	return tea.Tick(m.interval, func(time.Time) tea.Msg { return tickMsg{} })
+}
+
+func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		return m, nil
+
+	case tickMsg:
+		return m, tea.Batch(m.refresh(), m.tick())
+
+	case refreshMsg:
+		if msg.err != nil {
+			m.listErr = msg.err
+			return m, nil
+		}
+		rows := ui.BuildStatusRows(m.vms, msg.actual)
+		if !m.refreshed.IsZero() {
+			for _, t := range ui.StatusTransitions(m.rows, rows, msg.at) {
+				m.log(t.Name, t.Change, false)
+			}
+		}
+		m.actual, m.rows, m.refreshed, m.listErr = msg.actual, rows, msg.at, nil
+		return m, nil
+
+	case opDoneMsg:
+		delete(m.busy, msg.name)
+		if msg.err != nil {
+			m.log(msg.name, msg.op+" failed: "+msg.err.Error(), true)
+		} else {
+			m.log(msg.name, msg.done, false)
+		}
+		return m, m.refresh()
+
+	case sshReadyMsg:
+		if msg.err != nil {
+			delete(m.busy, msg.name)
+			m.log(msg.name, "ssh failed: "+msg.err.Error(), true)
+			return m, nil
+		}
+		m.log(msg.name, "ssh session started", false)
+		name := msg.name
+		return m, tea.ExecProcess(msg.cmd, func(err error) tea.Msg {
+			return opDoneMsg{name: name, op: "ssh", done: "ssh session ended", err: err}
+		})
+
+	case tea.KeyMsg:
+		return m.handleKey(msg)
+	}
+	return m, nil
+}
+
+func (m Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	key := msg.String()
+	if key == "ctrl+c" {
+		return m, tea.Quit
+	}
+	vm, ok := m.selected()
+
+	if m.confirm {
+		m.confirm = false
+		if key != "y" {
+			m.log(vm.Name, "destroy cancelled", false)
+			return m, nil
+		}
+		return m.run(vm, "destroy", "destroyed", m.actions.Destroy)
+	}
+
+	switch key {
+	case "q":
+		return m, tea.Quit
+	case "up", "k":
+		m.cursor = max(m.cursor-1, 0)
+	case "down", "j":
+		m.cursor = min(m.cursor+1, max(len(m.vms)-1, 0))
+	case "home", "g":
+		m.cursor = 0
+	case "end", "G":
+		m.cursor = max(len(m.vms)-1, 0)
+	case "r":
+		return m, m.refresh()
+	}
+	if !ok {
+		return m, nil
+	}
+
+	switch key {
+	case "s":
+		return m.run(vm, "start", "started", m.actions.Start)
+	case "x":
+		return m.run(vm, "stop", "stopped", m.actions.Stop)
+	case "d":
+		if op, busy := m.busy[vm.Name]; busy {
+			m.log(vm.Name, op+" is still running", true)
+			return m, nil
+		}
+		m.confirm = true
+	case "enter", "c":
+		if op, busy := m.busy[vm.Name]; busy {
+			m.log(vm.Name, op+" is still running", true)
+			return m, nil
+		}
+		m.busy[vm.Name] = "ssh"
+		m.log(vm.Name, "connecting...", false)
+		connect := m.actions.SSH
+		return m, func() tea.Msg {
+			cmd, err := connect(vm)
+			return sshReadyMsg{name: vm.Name, cmd: cmd, err: err}
+		}
+	}
+	return m, nil
+}
+
+// run starts op on vm in the background unless another operation on it is
+// still running.
+func (m Model) run(vm fleet.ResolvedVM, op, done string, fn func(fleet.ResolvedVM) error) (tea.Model, tea.Cmd) {
+	if running, busy := m.busy[vm.Name]; busy {
+		m.log(vm.Name, running+" is still running", true)
+		return m, nil
+	}
+	m.busy[vm.Name] = op
+	m.log(vm.Name, op+"...", false)
+	return m, func() tea.Msg {
+		return opDoneMsg{name: vm.Name, op: op, done: done, err: fn(vm)}
+	}
+}
+
+// selected returns the VM under the cursor.
+func (m Model) selected() (fleet.ResolvedVM, bool) {
+	if m.cursor >= len(m.vms) {
+		return fleet.ResolvedVM{}, false
+	}
+	return m.vms[m.cursor], true
+}
+
+// current returns the lume state of the named VM, if it exists.
+func (m Model) current(name string) *lume.VM {
+	for i := range m.actual {
+		if m.actual[i].Name == name {
+			return &m.actual[i]
+		}
+	}
+	return nil
+}
+
+// log appends a line to the log of the named VM. Output of failed lume
+// commands is folded onto the line.
+func (m Model) log(name, text string, isErr bool) {
+	entries := append(m.logs[name], LogEntry{Time: time.Now(), Text: strings.Join(strings.Fields(text), " "), Err: isErr})
+	if len(entries) > logLimit {
+		entries = entries[len(entries)-logLimit:]
+	}
+	m.logs[name] = entries
+}
@@ -0,0 +1,132 @@
+package tui
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/hoalong/lume-fleet/fleet"
+	"github.com/hoalong/lume-fleet/lume"
+)
+
+func key(s string) tea.KeyMsg {
+	switch s {
+	case "enter":
+		return tea.KeyMsg{Type: tea.KeyEnter}
+	case "down":
+		return tea.KeyMsg{Type: tea.KeyDown}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+// press sends keys to m and runs the commands they return that are not
+// ticks or batches.
+func press(t *testing.T, m Model, keys ...string) Model {
+	t.Helper()
+	for _, k := range keys {
+		next, cmd := m.Update(key(k))
+		m = next.(Model)
+		if cmd != nil {
+			if msg := cmd(); msg != nil {
+				next, _ = m.Update(msg)
+				m = next.(Model)
+			}
+		}
+	}
+	return m
+}
+
+func TestDestroyNeedsConfirmation(t *testing.T) {
+	var destroyed []string
+	actions := Actions{
+		List: func() ([]lume.VM, error) { return nil, nil },
+		Destroy: func(vm fleet.ResolvedVM) error {
+			destroyed = append(destroyed, vm.Name)
+			return nil
+		},
+	}
+	m := New([]fleet.ResolvedVM{{Name: "dev"}, {Name: "ci"}}, actions, time.Second)
+
+	m = press(t, m, "d", "n")
+	if len(destroyed) != 0 {
+		t.Fatalf("destroyed %v after cancelling, want nothing", destroyed)
+	}
+	m = press(t, m, "down", "d", "y")
+	if len(destroyed) != 1 || destroyed[0] != "ci" {
+		t.Fatalf("destroyed %v, want [ci]", destroyed)
+	}
+
+	var log []string
+	for _, e := range m.logs["ci"] {
+		log = append(log, e.Text)
+	}
+	if got, want := strings.Join(log, "; "), "destroy...; destroyed"; got != want {
+		t.Fatalf("ci log = %q, want %q", got, want)
+	}
+}
+
+func TestFailedOperationIsLogged(t *testing.T) {
+	actions := Actions{
+		List: func() ([]lume.VM, error) { return nil, nil },
+		Start: func(vm fleet.ResolvedVM) error {
+			return errors.New("lume run \"dev\": \nno such VM\n")
+		},
+	}
+	m := press(t, New([]fleet.ResolvedVM{{Name: "dev"}}, actions, time.Second), "s")
+
+	entries := m.logs["dev"]
+	last := entries[len(entries)-1]
+	if !last.Err || last.Text != `start failed: lume run "dev": no such VM` {
+		t.Fatalf("last log entry = %+v, want folded start failure", last)
+	}
+	if _, busy := m.busy["dev"]; busy {
+		t.Fatalf("dev still busy after the start failed")
+	}
+}
+
+func TestRefreshLogsTransitions(t *testing.T) {
+	ip := "192.168.64.5"
+	m := New([]fleet.ResolvedVM{{Name: "dev"}}, Actions{}, time.Second)
+	for _, actual := range [][]lume.VM{
+		{{Name: "dev", Status: "stopped"}},
+		{{Name: "dev", Status: "running", IPAddress: &ip}},
+	} {
+		next, _ := m.Update(refreshMsg{actual: actual, at: time.Now()})
+		m = next.(Model)
+	}
+
+	var log []string
+	for _, e := range m.logs["dev"] {
+		log = append(log, e.Text)
+	}
+	if got, want := strings.Join(log, "; "), "stopped → running; IP acquired 192.168.64.5"; got != want {
+		t.Fatalf("dev log = %q, want %q", got, want)
+	}
+}
+
+func TestDetailRows(t *testing.T) {
+	vm := fleet.ResolvedVM{Name: "dev", OS: "macos", CPU: 4, Memory: "8GB", DiskSize: "50GB"}
+	drifted := func(current *lume.VM) []string {
+		var fields []string
+		for _, r := range detailRows(vm, current) {
+			if r.drift {
+				fields = append(fields, r.field)
+			}
+		}
+		return fields
+	}
+
+	if got := drifted(nil); got != nil {
+		t.Fatalf("detailRows(not created) drift = %v, want none", got)
+	}
+	matching := &lume.VM{OS: "macOS", CPUCount: 4, MemorySize: 8 << 30, DiskSize: &lume.DiskSize{Total: 50 << 30}}
+	if got := drifted(matching); got != nil {
+		t.Fatalf("detailRows(matching) drift = %v, want none", got)
+	}
+	changed := &lume.VM{OS: "macOS", CPUCount: 8, MemorySize: 16 << 30, DiskSize: &lume.DiskSize{Total: 50 << 30}}
+	if got := strings.Join(drifted(changed), ","); got != "cpu,memory" {
+		t.Fatalf("detailRows(changed) drift = %v, want cpu,memory", got)
+	}
+}
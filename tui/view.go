@@ -0,0 +1,276 @@
+package tui
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/hoalong/lume-fleet/fleet"
+	"github.com/hoalong/lume-fleet/lume"
+	"github.com/hoalong/lume-fleet/ui"
+)
+
+var (
+	red  = lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+	pane = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("238")).Padding(0, 1)
+)
+
+// listColumns are the columns of the VM list and their widths. The last
+// column takes the remaining width.
+var listColumns = []struct {
+	title string
+	width int
+}{
+	{"NAME", 20}, {"STATE", 12}, {"IP", 16}, {"OS", 6}, {"CPU", 5}, {"MEMORY", 8}, {"TAGS", 0},
+}
+
+// minPaneHeight is the height the detail and log panes keep when the VM
+// list is too long to show in full.
+const minPaneHeight = 8
+
+func (m Model) View() string {
+	if m.width == 0 {
+		return "loading..."
+	}
+
+	title := ui.Bold.Render(fmt.Sprintf("lume-fleet  %d VMs", len(m.vms)))
+	switch {
+	case m.listErr != nil:
+		title += "  " + red.Render("cannot list VMs via lume CLI: "+strings.Join(strings.Fields(m.listErr.Error()), " "))
+	case !m.refreshed.IsZero():
+		title += ui.Gray.Render(fmt.Sprintf("  updated %s, every %s", m.refreshed.Format(time.TimeOnly), m.interval))
+	}
+
+	footer := m.footer()
+	// Lines left after the title, its blank line and the footer.
+	avail := m.height - 3
+	listHeight := min(len(m.vms)+1, max(avail-minPaneHeight, 2))
+	paneHeight := max(avail-listHeight-1, 3)
+
+	list := m.renderList(listHeight)
+	leftWidth := m.width / 2
+	panes := lipgloss.JoinHorizontal(lipgloss.Top,
+		m.renderDetail(leftWidth, paneHeight),
+		m.renderLog(m.width-leftWidth, paneHeight),
+	)
+	return strings.Join([]string{title, "", list, panes, footer}, "\n")
+}
+
+// renderList renders the header and as many VM rows as fit into height,
+// scrolled to keep the cursor visible.
+func (m Model) renderList(height int) string {
+	cells := make([]string, len(listColumns))
+	for i, c := range listColumns {
+		cells[i] = c.title
+	}
+	lines := []string{ui.Header.Render(m.listLine("  ", cells))}
+
+	visible := max(height-1, 1)
+	first := max(m.cursor-visible+1, 0)
+	for i := first; i < len(m.vms) && i < first+visible; i++ {
+		r := m.rows[i]
+		state := ui.ColorizeState(r.State)
+		if op, ok := m.busy[r.Name]; ok {
+			state = ui.Yellow.Render(op + "...")
+		}
+		cells := []string{r.Name, state, r.IP, r.OS, strconv.Itoa(r.CPU), r.Memory, strings.Join(r.Tags, ", ")}
+		prefix := "  "
+		if i == m.cursor {
+			prefix = "▸ "
+			cells[0] = ui.Bold.Render(cells[0])
+		}
+		lines = append(lines, m.listLine(prefix, cells))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// listLine lays out one row of the VM list.
+func (m Model) listLine(prefix string, cells []string) string {
+	var sb strings.Builder
+	sb.WriteString(prefix)
+	used := lipgloss.Width(prefix)
+	for i, c := range listColumns {
+		width := c.width
+		if width == 0 {
+			width = max(m.width-used, 0)
+		}
+		sb.WriteString(lipgloss.NewStyle().Width(width).MaxWidth(width).Render(cells[i]))
+		used += width
+	}
+	return sb.String()
+}
+
+func (m Model) renderDetail(width, height int) string {
+	vm, ok := m.selected()
+	if !ok {
+		return pane.Width(width - 2).Height(height - 2).Render(ui.Gray.Render("no VMs"))
+	}
+	rows := detailRows(vm, m.current(vm.Name))
+
+	fieldWidth, specWidth := len("FIELD"), len("SPEC")
+	for _, r := range rows {
+		fieldWidth = max(fieldWidth, len(r.field))
+		specWidth = max(specWidth, lipgloss.Width(r.spec))
+	}
+	line := func(field, spec, actual string) string {
+		return fmt.Sprintf("%-*s  %s  %s", fieldWidth, field, lipgloss.NewStyle().Width(specWidth).Render(spec), actual)
+	}
+
+	lines := []string{ui.Bold.Render(vm.Name + ": spec vs actual"), ui.Header.Render(line("FIELD", "SPEC", "ACTUAL"))}
+	for _, r := range rows {
+		actual := r.actual
+		if r.drift {
+			actual = ui.Yellow.Render(actual + " *")
+		}
+		lines = append(lines, line(r.field, r.spec, actual))
+	}
+	return pane.Width(width - 2).Height(height - 2).MaxHeight(height).Render(strings.Join(lines, "\n"))
+}
+
+func (m Model) renderLog(width, height int) string {
+	vm, ok := m.selected()
+	if !ok {
+		return pane.Width(width - 2).Height(height - 2).Render("")
+	}
+	entries := m.logs[vm.Name]
+	// Border and title take three lines.
+	if n := height - 3; len(entries) > n {
+		entries = entries[len(entries)-max(n, 0):]
+	}
+
+	lines := []string{ui.Bold.Render("log: " + vm.Name)}
+	if len(entries) == 0 {
+		lines = append(lines, ui.Gray.Render("no operations yet"))
+	}
+	for _, e := range entries {
+		text := e.Text
+		if e.Err {
+			text = red.Render(text)
+		}
+		lines = append(lines, ui.Gray.Render(e.Time.Format(time.TimeOnly))+"  "+text)
+	}
+	return pane.Width(width - 2).Height(height - 2).MaxHeight(height).Render(strings.Join(lines, "\n"))
+}
+
+func (m Model) footer() string {
+	if m.confirm {
+		vm, _ := m.selected()
+		return red.Render(fmt.Sprintf("Destroy %s? This is irreversible. Press y to confirm, any other key to cancel.", vm.Name))
+	}
+	return ui.Gray.Render("↑/↓ select · s start · x stop · d destroy · enter ssh · r refresh · q quit")
+}
+
+// detailRow compares one field of the resolved spec with the existing VM.
+type detailRow struct {
+	field, spec, actual string
+	// drift is set when the existing VM no longer matches the spec.
+	drift bool
+}
+
+// detailRows returns the resolved spec of vm next to the state lume
+// reports. current is nil when the VM is not created.
+func detailRows(vm fleet.ResolvedVM, current *lume.VM) []detailRow {
+	// actual returns a field of the existing VM, or - when it is not created.
+	actual := func(field func(*lume.VM) string) string {
+		if current == nil {
+			return "-"
+		}
+		return ui.OrDash(field(current))
+	}
+	// differs reports whether the existing VM no longer matches the spec.
+	differs := func(field func(*lume.VM) bool) bool {
+		return current != nil && field(current)
+	}
+	sizeDiffers := func(spec string, actualMB int64) bool {
+		want, err := fleet.ParseSize(spec)
+		return err == nil && actualMB != want
+	}
+
+	autostart := "autostart"
+	if !vm.Autostart {
+		autostart = "manual start"
+	}
+	vnc := "auto"
+	if vm.VNCPort != 0 {
+		vnc = strconv.Itoa(vm.VNCPort)
+	}
+	var paths []string
+	for _, d := range vm.SharedDirs {
+		paths = append(paths, d.Path)
+	}
+	ssh := vm.SSH.User
+	if vm.SSH.Port != 0 {
+		ssh += ":" + strconv.Itoa(vm.SSH.Port)
+	}
+	var labels []string
+	for _, k := range slices.Sorted(maps.Keys(vm.Labels)) {
+		labels = append(labels, k+"="+vm.Labels[k])
+	}
+
+	state := "not created"
+	if current != nil {
+		state = current.Status
+	}
+	return []detailRow{
+		{field: "state", spec: autostart, actual: state},
+		{
+			field: "os", spec: vm.OS,
+			actual: actual(func(v *lume.VM) string { return v.OS }),
+			drift:  differs(func(v *lume.VM) bool { return !strings.EqualFold(v.OS, vm.OS) }),
+		},
+		{
+			field: "size", spec: ui.OrDash(vm.Size),
+			actual: actual(func(v *lume.VM) string {
+				if len(fleet.SizeDrift(vm, *v)) > 0 {
+					return "changed"
+				}
+				return ""
+			}),
+			drift: differs(func(v *lume.VM) bool { return len(fleet.SizeDrift(vm, *v)) > 0 }),
+		},
+		{
+			field: "cpu", spec: strconv.Itoa(vm.CPU),
+			actual: actual(func(v *lume.VM) string { return strconv.Itoa(v.CPUCount) }),
+			drift:  differs(func(v *lume.VM) bool { return v.CPUCount != vm.CPU }),
+		},
+		{
+			field: "memory", spec: vm.Memory,
+			actual: actual(func(v *lume.VM) string { return fleet.FormatSize(v.MemorySize / (1024 * 1024)) }),
+			drift:  differs(func(v *lume.VM) bool { return sizeDiffers(vm.Memory, v.MemorySize/(1024*1024)) }),
+		},
+		{
+			field: "disk-size", spec: vm.DiskSize,
+			actual: actual(func(v *lume.VM) string {
+				if v.DiskSize == nil {
+					return ""
+				}
+				return fleet.FormatSize(v.DiskSize.Total / (1024 * 1024))
+			}),
+			drift: differs(func(v *lume.VM) bool {
+				return v.DiskSize != nil && sizeDiffers(vm.DiskSize, v.DiskSize.Total/(1024*1024))
+			}),
+		},
+		{field: "storage", spec: ui.OrDash(vm.Storage), actual: actual(func(v *lume.VM) string { return v.LocationName })},
+		{field: "ip", spec: "-", actual: actual(func(v *lume.VM) string { return deref(v.IPAddress) })},
+		{field: "vnc", spec: vnc, actual: actual(func(v *lume.VM) string { return deref(v.VNCUrl) })},
+		{
+			field: "shared-dirs", spec: ui.OrDash(strings.Join(paths, ", ")),
+			actual: actual(func(v *lume.VM) string { return strings.Join(v.SharedDirectories, ", ") }),
+		},
+		{field: "ssh", spec: ssh, actual: "-"},
+		{field: "tags", spec: ui.OrDash(strings.Join(vm.Tags, ", ")), actual: "-"},
+		{field: "labels", spec: ui.OrDash(strings.Join(labels, ", ")), actual: "-"},
+		{field: "priority", spec: strconv.Itoa(vm.Priority), actual: "-"},
+	}
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -10,11 +10,14 @@ import (
 	"github.com/hoalong/lume-fleet/lume"
 )
 
+// Styles shared by the status table, status --watch and the dashboard.
 var (
-	green  = lipgloss.NewStyle().Foreground(lipgloss.Color("42"))
-	yellow = lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
-	gray   = lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
-	bold   = lipgloss.NewStyle().Bold(true)
+	Green  = lipgloss.NewStyle().Foreground(lipgloss.Color("42"))
+	Yellow = lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
+	Gray   = lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+	Bold   = lipgloss.NewStyle().Bold(true)
+	Header = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("99"))
+
 	dimBorder = lipgloss.NewStyle().Foreground(lipgloss.Color("238"))
 )
 
@@ -24,9 +27,19 @@ type StatusRow struct {
 	State  string
 	IP     string
 	OS     string
+	Size   string
 	CPU    int
 	Memory string
 	Tags   []string
+
+	Labels     map[string]string
+	SharedDirs []fleet.SharedDir
+	// VNC is the VNC URL lume reports for a running VM.
+	VNC string
+
+	// SizeDrift lists resources of the existing VM that no longer match
+	// its size preset.
+	SizeDrift []string
 }
 
 // BuildStatusRows merges resolved fleet VMs with actual Lume state.
@@ -41,9 +54,13 @@ func BuildStatusRows(resolved []fleet.ResolvedVM, actual []lume.VM) []StatusRow
 		row := StatusRow{
 			Name:   r.Name,
 			OS:     r.OS,
+			Size:   r.Size,
 			CPU:    r.CPU,
 			Memory: r.Memory,
 			Tags:   r.Tags,
+
+			Labels:     r.Labels,
+			SharedDirs: r.SharedDirs,
 		}
 
 		if vm, ok := index[r.Name]; ok {
@@ -53,6 +70,10 @@ func BuildStatusRows(resolved []fleet.ResolvedVM, actual []lume.VM) []StatusRow
 			}
 			row.CPU = vm.CPUCount
 			row.Memory = formatBytes(vm.MemorySize)
+			row.SizeDrift = fleet.SizeDrift(r, vm)
+			if vm.VNCUrl != nil {
+				row.VNC = *vm.VNCUrl
+			}
 		} else {
 			row.State = "not created"
 		}
@@ -67,25 +88,34 @@ func BuildStatusRows(resolved []fleet.ResolvedVM, actual []lume.VM) []StatusRow
 	return rows
 }
 
-// RenderStatusTable outputs a formatted status table.
-func RenderStatusTable(rows []StatusRow, macosRunning int) string {
+// RenderStatusTable outputs a formatted status table. wide adds the VNC
+// column.
+func RenderStatusTable(rows []StatusRow, macosRunning, macosLimit int, wide bool) string {
 	var sb strings.Builder
 
-	header := fmt.Sprintf("  Fleet Status (%d VMs)  |  macOS: %d/2 slots", len(rows), macosRunning)
-	sb.WriteString(bold.Render(header))
+	header := fmt.Sprintf("  Fleet Status (%d VMs)  |  macOS: %d/%d slots", len(rows), macosRunning, macosLimit)
+	sb.WriteString(Bold.Render(header))
 	sb.WriteString("\n\n")
 
 	tableRows := make([][]string, len(rows))
 	for i, r := range rows {
 		tableRows[i] = []string{
 			r.Name,
-			colorizeState(r.State),
+			ColorizeState(r.State),
 			r.IP,
 			r.OS,
+			renderSize(r),
 			fmt.Sprintf("%d", r.CPU),
 			r.Memory,
 			strings.Join(r.Tags, ", "),
 		}
+		if wide {
+			tableRows[i] = append(tableRows[i], OrDash(r.VNC))
+		}
+	}
+	headers := []string{"NAME", "STATE", "IP", "OS", "SIZE", "CPU", "MEMORY", "TAGS"}
+	if wide {
+		headers = append(headers, "VNC")
 	}
 
 	t := table.New().
@@ -93,25 +123,47 @@ func RenderStatusTable(rows []StatusRow, macosRunning int) string {
 		BorderStyle(dimBorder).
 		StyleFunc(func(row, col int) lipgloss.Style {
 			if row == table.HeaderRow {
-				return lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("99")).Padding(0, 1)
+				return Header.Padding(0, 1)
 			}
 			return lipgloss.NewStyle().Padding(0, 1)
 		}).
-		Headers("NAME", "STATE", "IP", "OS", "CPU", "MEMORY", "TAGS").
+		Headers(headers...).
 		Rows(tableRows...)
 
 	sb.WriteString(t.String())
 	return sb.String()
 }
 
-func colorizeState(s string) string {
+// ColorizeState renders a lume VM state: running green, stopped yellow and
+// anything else gray.
+func ColorizeState(s string) string {
 	switch strings.ToLower(s) {
 	case "running":
-		return green.Render("running")
+		return Green.Render("running")
 	case "stopped":
-		return yellow.Render("stopped")
+		return Yellow.Render("stopped")
+	default:
+		return Gray.Render(s)
+	}
+}
+
+// OrDash returns s, or "-" when it is empty.
+func OrDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// renderSize shows the size preset, marked when the VM has drifted from it.
+func renderSize(r StatusRow) string {
+	switch {
+	case r.Size == "":
+		return "-"
+	case len(r.SizeDrift) > 0:
+		return Yellow.Render(r.Size + "*")
 	default:
-		return gray.Render(s)
+		return r.Size
 	}
 }
 
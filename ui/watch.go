@@ -0,0 +1,73 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Transition is a change of a VM's state or IP address between two polls.
+type Transition struct {
+	Time   time.Time
+	Name   string
+	Change string
+}
+
+func (t Transition) String() string {
+	return fmt.Sprintf("%s  %s: %s", t.Time.Format(time.TimeOnly), t.Name, t.Change)
+}
+
+// StatusTransitions returns the changes from prev to next, in the order of
+// next. VMs missing from prev are not reported.
+func StatusTransitions(prev, next []StatusRow, now time.Time) []Transition {
+	index := make(map[string]StatusRow, len(prev))
+	for _, r := range prev {
+		index[r.Name] = r
+	}
+
+	var transitions []Transition
+	for _, r := range next {
+		old, ok := index[r.Name]
+		if !ok {
+			continue
+		}
+		if !strings.EqualFold(old.State, r.State) {
+			transitions = append(transitions, Transition{now, r.Name, old.State + " → " + r.State})
+		}
+		switch {
+		case old.IP == r.IP:
+		case old.IP == "-":
+			transitions = append(transitions, Transition{now, r.Name, "IP acquired " + r.IP})
+		case r.IP == "-":
+			transitions = append(transitions, Transition{now, r.Name, "IP released " + old.IP})
+		default:
+			transitions = append(transitions, Transition{now, r.Name, "IP " + old.IP + " → " + r.IP})
+		}
+	}
+	return transitions
+}
+
+// RenderTransitions lists transitions oldest first. Those at or after since
+// are highlighted.
+func RenderTransitions(transitions []Transition, since time.Time) string {
+	var sb strings.Builder
+	sb.WriteString(Bold.Render("  Recent changes"))
+	sb.WriteString("\n")
+	if len(transitions) == 0 {
+		sb.WriteString(Gray.Render("  none yet"))
+		return sb.String()
+	}
+	for i, t := range transitions {
+		line := "  " + t.String()
+		if t.Time.Before(since) {
+			line = Gray.Render(line)
+		} else {
+			line = Green.Render(line)
+		}
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(line)
+	}
+	return sb.String()
+}
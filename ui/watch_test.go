@@ -0,0 +1,39 @@
+package ui
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestStatusTransitions(t *testing.T) {
+	prev := []StatusRow{
+		{Name: "dev", State: "stopped", IP: "-"},
+		{Name: "ci", State: "running", IP: "192.168.64.3"},
+		{Name: "build", State: "running", IP: "192.168.64.4"},
+		{Name: "idle", State: "stopped", IP: "-"},
+	}
+	next := []StatusRow{
+		{Name: "dev", State: "running", IP: "192.168.64.5"},
+		{Name: "ci", State: "stopped", IP: "-"},
+		{Name: "build", State: "running", IP: "192.168.64.6"},
+		{Name: "idle", State: "Stopped", IP: "-"},
+		{Name: "new", State: "running", IP: "192.168.64.7"},
+	}
+	now := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	var got []string
+	for _, tr := range StatusTransitions(prev, next, now) {
+		got = append(got, tr.String())
+	}
+	want := []string{
+		"15:04:05  dev: stopped → running",
+		"15:04:05  dev: IP acquired 192.168.64.5",
+		"15:04:05  ci: running → stopped",
+		"15:04:05  ci: IP released 192.168.64.3",
+		"15:04:05  build: IP 192.168.64.4 → 192.168.64.6",
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("StatusTransitions() = %q, want %q", got, want)
+	}
+}